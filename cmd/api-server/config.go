@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"spy-cat-agency/internal/application"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `usage: api-server config print [flags]
+
+prints the effective config as YAML with secrets redacted, then reports
+whatever would stop the server from starting`
+
+// loadConfig parses the config flags in args and returns the config with the
+// arguments left after the flags. A malformed value is fatal.
+func loadConfig(name string, args []string) (application.Config, []string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	loader := application.NewLoader(fs)
+	fs.Parse(args)
+
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return cfg, fs.Args()
+}
+
+// runConfig handles "api-server config ...".
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+
+	cfg, rest := loadConfig("config print", args[1:])
+	if len(rest) > 0 {
+		return errors.New(configUsage)
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg.Redacted()); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return cfg.Validate()
+}
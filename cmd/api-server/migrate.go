@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"spy-cat-agency/cmd/migrate/migrations"
+	"spy-cat-agency/internal/db"
+	"spy-cat-agency/internal/migrate"
+	"strconv"
+)
+
+const migrateUsage = `usage: api-server migrate [flags] <command>
+
+commands:
+  up          apply every pending migration
+  down [N]    roll back the last N migrations, 1 by default
+  goto V      migrate up or down to version V, 0 rolls back everything
+  force V     record version V as applied without running anything
+  status      print the applied version and pending migrations
+
+flags are the server ones, see api-server -h`
+
+var errUsage = errors.New(migrateUsage)
+
+// runMigrate handles "api-server migrate ..." against the configured database.
+func runMigrate(args []string) error {
+	cfg, args := loadConfig("migrate", args)
+	if len(args) == 0 {
+		return errUsage
+	}
+	if err := cfg.DB.Validate(); err != nil {
+		return err
+	}
+
+	conn, err := db.New(
+		cfg.DB.Addr,
+		cfg.DB.MaxOpenConns,
+		cfg.DB.MaxIdleConns,
+		cfg.DB.MaxIdleTime,
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := migrate.New(conn, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	version := func() (uint, error) {
+		if len(args) != 2 {
+			return 0, errUsage
+		}
+		v, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q", args[1])
+		}
+		return uint(v), nil
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "goto":
+		v, err := version()
+		if err != nil {
+			return err
+		}
+		return migrator.Goto(ctx, v)
+	case "force":
+		v, err := version()
+		if err != nil {
+			return err
+		}
+		return migrator.Force(ctx, v)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		dirty := ""
+		if status.Dirty {
+			dirty = " (dirty)"
+		}
+		fmt.Printf("version: %d%s, latest: %d\n", status.Version, dirty, status.Latest)
+		for _, m := range status.Pending {
+			fmt.Printf("pending: %s\n", m)
+		}
+		return nil
+	default:
+		return errUsage
+	}
+}
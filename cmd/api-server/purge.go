@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"spy-cat-agency/internal/db"
+	"spy-cat-agency/internal/store"
+	"time"
+)
+
+const purgeUsage = `usage: api-server purge [flags]
+
+removes cats and missions deleted longer than the retention ago, for good
+
+flags are the server ones, see api-server -h`
+
+// runPurge handles "api-server purge" against the configured database.
+func runPurge(args []string) error {
+	cfg, args := loadConfig("purge", args)
+	if len(args) > 0 {
+		return errors.New(purgeUsage)
+	}
+	if err := cfg.DB.Validate(); err != nil {
+		return err
+	}
+	if cfg.Retention <= 0 {
+		return fmt.Errorf("config: retention must be positive, got %v", cfg.Retention)
+	}
+
+	conn, err := db.New(
+		cfg.DB.Addr,
+		cfg.DB.MaxOpenConns,
+		cfg.DB.MaxIdleConns,
+		cfg.DB.MaxIdleTime,
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	storage := store.NewStorage(conn)
+	before := time.Now().UTC().Add(-cfg.Retention)
+	ctx := context.Background()
+
+	missions, err := storage.Mission.Purge(ctx, before)
+	if err != nil {
+		return err
+	}
+	cats, err := storage.Cat.Purge(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d cats and %d missions deleted before %s\n", cats, missions, before.Format(time.RFC3339))
+	return nil
+}
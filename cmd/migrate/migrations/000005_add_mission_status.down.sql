@@ -0,0 +1,8 @@
+ALTER TABLE missions ADD COLUMN is_complete BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE missions SET is_complete = (status = 'completed');
+
+ALTER TABLE missions
+    DROP COLUMN status,
+    DROP COLUMN assigned_at,
+    DROP COLUMN completed_at;
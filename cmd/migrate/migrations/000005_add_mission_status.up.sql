@@ -0,0 +1,16 @@
+ALTER TABLE missions
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'assigned', 'in_progress', 'completed', 'aborted')),
+    ADD COLUMN assigned_at TIMESTAMP,
+    ADD COLUMN completed_at TIMESTAMP;
+
+-- the old schema never recorded when missions were assigned or completed,
+-- so those times stay unknown rather than made up
+UPDATE missions
+SET status = CASE
+        WHEN is_complete THEN 'completed'
+        WHEN cat_id IS NOT NULL THEN 'assigned'
+        ELSE 'draft'
+    END;
+
+ALTER TABLE missions DROP COLUMN is_complete;
@@ -0,0 +1,4 @@
+-- a cat can run only one mission at a time
+CREATE UNIQUE INDEX IF NOT EXISTS missions_active_cat_idx
+    ON missions (cat_id)
+    WHERE status IN ('assigned', 'in_progress');
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS salary_history (
+    id bigserial PRIMARY KEY,
+    cat_id BIGINT NOT NULL REFERENCES cats(id) ON DELETE CASCADE,
+    salary DECIMAL(10,2) NOT NULL CHECK (salary >= 0),
+    effective_from TIMESTAMP NOT NULL DEFAULT now(),
+    changed_by VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS salary_history_cat_idx
+    ON salary_history (cat_id, effective_from);
+
+-- earlier salaries are lost, the current one is known from now on
+INSERT INTO salary_history (cat_id, salary, changed_by)
+SELECT id, salary, 'migration'
+FROM cats;
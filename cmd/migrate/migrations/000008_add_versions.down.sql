@@ -0,0 +1,8 @@
+ALTER TABLE targets
+    DROP COLUMN IF EXISTS version;
+
+ALTER TABLE missions
+    DROP COLUMN IF EXISTS version;
+
+ALTER TABLE cats
+    DROP COLUMN IF EXISTS version;
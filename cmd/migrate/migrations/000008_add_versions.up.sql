@@ -0,0 +1,9 @@
+-- bumped by every write, clients send it back in If-Match
+ALTER TABLE cats
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE missions
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE targets
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    entity VARCHAR(32) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    -- the row before and after the change, NULL when there is none
+    before JSONB,
+    after JSONB,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx
+    ON audit_log (entity, entity_id, id);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx
+    ON audit_log (actor, id);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx
+    ON audit_log (created_at);
+
+-- entries are never changed nor removed once written
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,20 @@
+-- soft-deleted rows can still be restored, so refuse to roll back over them
+-- instead of dropping what tells them apart. Purge or restore them first.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM cats WHERE deleted_at IS NOT NULL)
+        OR EXISTS (SELECT 1 FROM missions WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'soft-deleted cats or missions exist, run api-server purge or restore them before rolling back';
+    END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS missions_deleted_at_idx;
+
+DROP INDEX IF EXISTS cats_deleted_at_idx;
+
+ALTER TABLE missions
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE cats
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,12 @@
+-- deleted rows stay until the purge removes them for good
+ALTER TABLE cats
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE missions
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS cats_deleted_at_idx
+    ON cats (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS missions_deleted_at_idx
+    ON missions (deleted_at) WHERE deleted_at IS NOT NULL;
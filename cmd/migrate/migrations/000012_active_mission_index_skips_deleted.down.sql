@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS missions_active_cat_idx;
+
+CREATE UNIQUE INDEX IF NOT EXISTS missions_active_cat_idx
+    ON missions (cat_id)
+    WHERE status IN ('assigned', 'in_progress');
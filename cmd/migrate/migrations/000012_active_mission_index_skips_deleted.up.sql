@@ -0,0 +1,6 @@
+-- deleted missions don't keep their cat busy
+DROP INDEX IF EXISTS missions_active_cat_idx;
+
+CREATE UNIQUE INDEX IF NOT EXISTS missions_active_cat_idx
+    ON missions (cat_id)
+    WHERE status IN ('assigned', 'in_progress') AND deleted_at IS NULL;
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"spy-cat-agency/internal/application"
+	"spy-cat-agency/internal/auth"
+	"time"
+)
+
+// token mints a JWT signed with the configured signing key, e.g.
+//
+//	go run ./cmd/token -role cat -cat 3
+func main() {
+	role := flag.String("role", string(auth.RoleStaff), "staff or cat")
+	subject := flag.String("sub", "", "token subject, defaults to the role")
+	catID := flag.Int64("cat", 0, "cat ID, required for the cat role")
+	ttl := flag.Duration("ttl", 24*time.Hour, "token lifetime")
+	loader := application.NewLoader(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authenticator, err := auth.New([]byte(cfg.Auth.SigningKey), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *subject == "" {
+		*subject = *role
+		if *catID != 0 {
+			*subject = fmt.Sprintf("cat:%d", *catID)
+		}
+	}
+
+	token, err := authenticator.Issue(auth.Principal{
+		Subject: *subject,
+		Role:    auth.Role(*role),
+		CatID:   *catID,
+	}, *ttl)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(token)
+}
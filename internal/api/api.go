@@ -3,39 +3,77 @@ package api
 import (
 	"spy-cat-agency/internal/api/handlers"
 	"spy-cat-agency/internal/api/middleware"
+	"spy-cat-agency/internal/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Mount(router *gin.Engine) {
+// Mount registers the routes served by h on router.
+func Mount(router *gin.Engine, h *handlers.Handler) {
+	router.GET("/metrics", handlers.Metrics(h.Metrics)) // prometheus metrics
+	router.GET("/healthz", handlers.Healthz)            // liveness probe
+	router.GET("/readyz", h.Readyz)                     // readiness probe
+	router.GET("/version", h.Version)                   // build info and schema version
+
 	apiV1 := router.Group("/v1")
 
-	apiV1.Use(middleware.Logger())
+	apiV1.Use(middleware.Logger(h.Logger, h.Now), middleware.Metrics(h.Metrics, h.Now))
+
+	apiV1.GET("/openapi.json", handlers.OpenAPISpec(router)) // openapi document
+	apiV1.GET("/docs", handlers.DocsPage)                    // docs page
+
+	// agency staff manage cats and missions
+	staff := apiV1.Group("")
+	staff.Use(middleware.Authenticate(h.Auth), middleware.RequireRole(auth.RoleStaff))
+
+	// creates may be retried with an Idempotency-Key
+	idempotent := middleware.Idempotent(h.Store, h.IdempotencyTTL, h.Logger)
 
-	cats := apiV1.Group("/cats")
+	cats := staff.Group("/cats")
 	cats.Use(middleware.ExtractID("catID"))
-	cats.GET("/", handlers.GetAllCats)         // get all
-	cats.GET("/:catID", handlers.GetCatByID)   // get by id
-	cats.POST("/", handlers.CreateCat)         // create
-	cats.PUT("/:catID", handlers.UpdateCat)    // update
-	cats.DELETE("/:catID", handlers.DeleteCat) // delete
+	cats.GET("/", h.GetAllCats)                                      // get all
+	cats.GET("/:catID", h.GetCatByID)                                // get by id
+	cats.POST("/", idempotent, h.CreateCat)                          // create
+	cats.PUT("/:catID", middleware.RequireIfMatch(), h.UpdateCat)    // update
+	cats.DELETE("/:catID", middleware.RequireIfMatch(), h.DeleteCat) // delete
+	cats.POST("/:catID/restore", h.RestoreCat)                       // undo delete
 
-	missions := apiV1.Group("/missions")
+	cats.GET("/:catID/salary-history", h.GetCatSalaryHistory) // salary changes, oldest first
+
+	staff.GET("/payroll", h.GetPayroll) // monthly payroll by breed and experience
+	staff.GET("/audit", h.GetAudit)     // audit log, newest first
+
+	missions := staff.Group("/missions")
 	missions.Use(middleware.ExtractID("missionID"))
-	missions.GET("/", handlers.GetAllMissions)             // get all
-	missions.POST("/", handlers.CreateMission)             // create
-	missions.GET("/:missionID", handlers.GetMissionByID)   // get by id
-	missions.PUT("/:missionID", handlers.UpdateMission)    // update
-	missions.DELETE("/:missionID", handlers.DeleteMission) // delete
+	missions.GET("/", h.GetAllMissions)                                          // get all
+	missions.POST("/", idempotent, h.CreateMission)                              // create
+	missions.GET("/:missionID", h.GetMissionByID)                                // get by id
+	missions.DELETE("/:missionID", middleware.RequireIfMatch(), h.DeleteMission) // delete
 
 	catMission := missions.Group("/:missionID")
 	catMission.Use(middleware.ExtractID("catID"))
-	catMission.PUT("/:catID/assign", handlers.AssignCatForMission) // assign cat for mission
+	catMission.PUT("/:catID/assign", middleware.RequireIfMatch(), h.AssignCatForMission) // assign cat for mission
+	catMission.POST("/start", h.StartMission)                                            // assigned -> in_progress
+	catMission.POST("/complete", h.CompleteMission)                                      // in_progress -> completed
+	catMission.POST("/abort", h.AbortMission)                                            // draft, assigned or in_progress -> aborted
+	catMission.POST("/restore", h.RestoreMission)                                        // undo delete
 
 	targets := missions.Group("/targets")
 	targets.Use(middleware.ExtractID("targetID"))
-	targets.POST("/:missionID", handlers.AddMissionTarget)     // add mission target
-	targets.POST("note/:targetID", handlers.AddNoteOnTarget)   // update note on target
-	targets.PUT("/:targetID", handlers.UpdateMissionTarget)    // update target
-	targets.DELETE("/:targetID", handlers.DeleteMissionTarget) // delete mission target
+	targets.POST("/:missionID", idempotent, h.AddMissionTarget)                      // add mission target
+	targets.DELETE("/:targetID", middleware.RequireIfMatch(), h.DeleteMissionTarget) // delete mission target
+
+	notes := targets.Group("note/:targetID")
+	notes.Use(middleware.ExtractID("noteID"))
+	notes.GET("", h.GetTargetNotes)        // get all target notes
+	notes.GET("/:noteID", h.GetNoteByID)   // get note by id
+	notes.PUT("/:noteID", h.UpdateNote)    // update note
+	notes.DELETE("/:noteID", h.DeleteNote) // delete note
+
+	// staff and the cat running the mission work on its targets
+	field := apiV1.Group("/missions/targets")
+	field.Use(middleware.Authenticate(h.Auth), middleware.ExtractID("targetID"), middleware.RequireTargetAccess(h.Store, h.Logger))
+	field.GET("/:targetID", h.GetMissionTarget)                                 // get target
+	field.PUT("/:targetID", middleware.RequireIfMatch(), h.UpdateMissionTarget) // update target
+	field.POST("note/:targetID", idempotent, h.AddNoteOnTarget)                 // add note on target
 }
@@ -0,0 +1,208 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"spy-cat-agency/internal/api"
+	"spy-cat-agency/internal/api/handlers"
+	"spy-cat-agency/internal/auth"
+	"spy-cat-agency/internal/db"
+	"spy-cat-agency/internal/store"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testAPIKey = "concurrency-test-key"
+
+// The concurrency tests need a migrated Postgres, point TEST_DB_ADDR at it.
+func setupConcurrency(t *testing.T) (*gin.Engine, store.Storage) {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR is not set")
+	}
+
+	conn, err := db.New(addr, 50, 50, "1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	storage := store.NewStorage(conn)
+
+	authenticator, err := auth.New([]byte("concurrency-test-signing-key-0123456789"), map[string]string{"test": testAPIKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.Mount(router, handlers.New(storage, nil, authenticator, nil, nil, nil, nil))
+
+	return router, storage
+}
+
+// hammer fires all requests at once as staff and returns the response codes.
+func hammer(router *gin.Engine, requests []*http.Request) []int {
+	codes := make([]int, len(requests))
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		req.Header.Set("X-API-Key", testAPIKey)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	return codes
+}
+
+func countCode(codes []int, code int) int {
+	n := 0
+	for _, c := range codes {
+		if c == code {
+			n++
+		}
+	}
+	return n
+}
+
+func createCat(t *testing.T, storage store.Storage) *store.Cat {
+	t.Helper()
+
+	cat := &store.Cat{Name: "Hammer", YearsOfExperience: 3, Breed: "Bengal", Salary: 1000}
+	if err := storage.Cat.Create(context.Background(), cat); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Cat.Delete(context.Background(), cat.ID, store.AnyVersion) })
+
+	return cat
+}
+
+func createMission(t *testing.T, storage store.Storage, targets int) *store.Mission {
+	t.Helper()
+
+	mission := &store.Mission{}
+	for i := range targets {
+		mission.Targets = append(mission.Targets, store.Target{
+			Name:    fmt.Sprintf("Target_%d", i),
+			Country: "Ukraine",
+		})
+	}
+
+	if err := storage.Mission.Create(context.Background(), mission); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Mission.Delete(context.Background(), mission.ID, store.AnyVersion) })
+
+	return mission
+}
+
+func TestConcurrentAssignSameCat(t *testing.T) {
+	router, storage := setupConcurrency(t)
+
+	cat := createCat(t, storage)
+
+	var requests []*http.Request
+	for range 20 {
+		mission := createMission(t, storage, 1)
+		url := fmt.Sprintf("/v1/missions/%d/%d/assign", mission.ID, cat.ID)
+		requests = append(requests, httptest.NewRequest(http.MethodPut, url, nil))
+	}
+
+	codes := hammer(router, requests)
+
+	if ok := countCode(codes, http.StatusOK); ok != 1 {
+		t.Fatalf("expected exactly one assignment, got %d (%v)", ok, codes)
+	}
+}
+
+func TestConcurrentAssignSameMission(t *testing.T) {
+	router, storage := setupConcurrency(t)
+
+	mission := createMission(t, storage, 1)
+
+	var requests []*http.Request
+	for range 20 {
+		cat := createCat(t, storage)
+		url := fmt.Sprintf("/v1/missions/%d/%d/assign", mission.ID, cat.ID)
+		requests = append(requests, httptest.NewRequest(http.MethodPut, url, nil))
+	}
+
+	codes := hammer(router, requests)
+
+	if ok := countCode(codes, http.StatusOK); ok != 1 {
+		t.Fatalf("expected exactly one assignment, got %d (%v)", ok, codes)
+	}
+}
+
+func TestConcurrentAddTargets(t *testing.T) {
+	router, storage := setupConcurrency(t)
+
+	mission := createMission(t, storage, 1)
+
+	var requests []*http.Request
+	for i := range 20 {
+		body := fmt.Sprintf(`{"name": "Extra_%d", "country": "Poland"}`, i)
+		url := fmt.Sprintf("/v1/missions/targets/%d", mission.ID)
+		requests = append(requests, httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body)))
+	}
+
+	codes := hammer(router, requests)
+
+	if ok := countCode(codes, http.StatusOK); ok != store.MaxMissionTargets-1 {
+		t.Fatalf("expected %d added targets, got %d (%v)", store.MaxMissionTargets-1, ok, codes)
+	}
+
+	count, err := storage.Mission.GetTargetsQuantity(context.Background(), mission.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != store.MaxMissionTargets {
+		t.Fatalf("expected %d targets, got %d", store.MaxMissionTargets, count)
+	}
+}
+
+func TestConcurrentDeleteTargets(t *testing.T) {
+	router, storage := setupConcurrency(t)
+
+	mission := createMission(t, storage, store.MaxMissionTargets)
+
+	var requests []*http.Request
+	for _, target := range mission.Targets {
+		url := fmt.Sprintf("/v1/missions/targets/%d", target.ID)
+		requests = append(requests, httptest.NewRequest(http.MethodDelete, url, nil))
+	}
+
+	codes := hammer(router, requests)
+
+	if ok := countCode(codes, http.StatusOK); ok != len(mission.Targets)-1 {
+		t.Fatalf("expected %d deleted targets, got %d (%v)", len(mission.Targets)-1, ok, codes)
+	}
+
+	count, err := storage.Mission.GetTargetsQuantity(context.Background(), mission.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected the last target to survive, got %d", count)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type requestListAudit struct {
+	Cursor   string            `form:"cursor"`
+	Limit    int               `form:"limit"`
+	Actor    string            `form:"actor"`
+	Action   store.AuditAction `form:"action"`
+	Entity   store.AuditEntity `form:"entity"`
+	EntityID int64             `form:"entity_id"`
+	// From and To are RFC 3339 times, From included and To excluded.
+	From string `form:"from"`
+	To   string `form:"to"`
+}
+
+// GetAudit lists the audit log newest first, page by page.
+func (h *Handler) GetAudit(c *gin.Context) {
+	var request requestListAudit
+	if err := c.ShouldBindQuery(&request); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Could not parse query parameters"))
+		return
+	}
+
+	var fields []problem.FieldError
+	if request.Limit < 0 || request.Limit > store.MaxPageLimit {
+		fields = append(fields, problem.FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", store.MaxPageLimit)})
+	}
+	if request.Action != "" && !slices.Contains(request.Action.Enum(), any(request.Action)) {
+		fields = append(fields, problem.FieldError{Field: "action", Message: fmt.Sprintf("must be one of %v", request.Action.Enum())})
+	}
+	if request.Entity != "" && !slices.Contains(request.Entity.Enum(), any(request.Entity)) {
+		fields = append(fields, problem.FieldError{Field: "entity", Message: fmt.Sprintf("must be one of %v", request.Entity.Enum())})
+	}
+	if request.EntityID < 0 {
+		fields = append(fields, problem.FieldError{Field: "entity_id", Message: "must be positive"})
+	}
+
+	instant := func(field, raw string) time.Time {
+		if raw == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fields = append(fields, problem.FieldError{Field: field, Message: "must be a time like 2024-01-02T15:04:05Z"})
+		}
+		return t
+	}
+	from, to := instant("from", request.From), instant("to", request.To)
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		fields = append(fields, problem.FieldError{Field: "to", Message: "must be after from"})
+	}
+
+	if len(fields) > 0 {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Invalid query parameters").WithFields(fields...))
+		return
+	}
+
+	filter := store.AuditFilter{
+		Actor:    request.Actor,
+		Action:   request.Action,
+		Entity:   request.Entity,
+		EntityID: request.EntityID,
+		From:     from,
+		To:       to,
+		Cursor:   request.Cursor,
+		Limit:    request.Limit,
+	}
+
+	page, err := h.Store.Audit.List(c.Request.Context(), filter)
+	if err != nil {
+		h.logError(c, err, "failed to list audit log")
+		problem.Abort(c, problem.From(err, problem.InternalError))
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
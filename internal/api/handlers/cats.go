@@ -1,117 +1,252 @@
 package handlers
 
 import (
-	"errors"
+	"fmt"
+	"math"
 	"net/http"
-	"spy-cat-agency/internal/application"
-	"spy-cat-agency/internal/breed"
+	"slices"
+	"spy-cat-agency/internal/api/middleware"
+	"spy-cat-agency/internal/api/problem"
 	"spy-cat-agency/internal/store"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type requestChangeSalary struct {
-	Salary float64 `json:"salary" validate:"required"`
+	Salary *float64 `json:"salary" validate:"required,gte=0,lte=99999999.99"`
 }
 
-type errorResponse struct {
-	Message string `json:"message"`
+type requestListCats struct {
+	Cursor         string   `form:"cursor"`
+	Limit          int      `form:"limit"`
+	Breed          string   `form:"breed"`
+	MinExperience  *int     `form:"min_experience"`
+	MaxExperience  *int     `form:"max_experience"`
+	MinSalary      *float64 `form:"min_salary"`
+	MaxSalary      *float64 `form:"max_salary"`
+	Sort           string   `form:"sort"`
+	Order          string   `form:"order"`
+	IncludeDeleted bool     `form:"include_deleted"`
 }
 
-func newErrorResponse(message string) errorResponse {
-	return errorResponse{Message: message}
-}
+func (h *Handler) GetAllCats(c *gin.Context) {
+	var request requestListCats
+	if err := c.ShouldBindQuery(&request); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Could not parse query parameters"))
+		return
+	}
+
+	if request.Limit < 0 || request.Limit > store.MaxPageLimit {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Invalid query parameters").
+			WithFields(problem.FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", store.MaxPageLimit)}))
+		return
+	}
 
-func GetAllCats(c *gin.Context) {
-	cats, err := application.App.Store.Cat.GetAll(c.Request.Context())
+	if request.Sort != "" && !slices.Contains(store.CatSortFields, request.Sort) {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Invalid query parameters").
+			WithFields(problem.FieldError{Field: "sort", Message: fmt.Sprintf("must be one of %v", store.CatSortFields)}))
+		return
+	}
+
+	if request.Order != "" && request.Order != "asc" && request.Order != "desc" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Invalid query parameters").
+			WithFields(problem.FieldError{Field: "order", Message: "must be asc or desc"}))
+		return
+	}
+
+	filter := store.CatFilter{
+		Breed:          request.Breed,
+		MinExperience:  request.MinExperience,
+		MaxExperience:  request.MaxExperience,
+		MinSalary:      request.MinSalary,
+		MaxSalary:      request.MaxSalary,
+		Sort:           request.Sort,
+		Desc:           request.Order == "desc",
+		Cursor:         request.Cursor,
+		Limit:          request.Limit,
+		IncludeDeleted: request.IncludeDeleted,
+	}
+
+	page, err := h.Store.Cat.List(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, newErrorResponse("Could not get all cats"))
+		h.logError(c, err, "failed to list cats")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
 		return
 	}
-	c.JSON(http.StatusOK, cats)
+	c.JSON(http.StatusOK, page)
 }
 
-func GetCatByID(c *gin.Context) {
-	cat, err := application.App.Store.Cat.GetByID(c.Request.Context(), c.GetInt64("catID"))
+func (h *Handler) GetCatByID(c *gin.Context) {
+	cat, err := h.Store.Cat.GetByID(c.Request.Context(), c.GetInt64("catID"))
 	if err != nil {
-		status := http.StatusInternalServerError
-		message := "Could not get cat"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Cat not found"
-		}
-
-		c.JSON(status, newErrorResponse(message))
+		h.logError(c, err, "failed to get cat by ID")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
 		return
 	}
+	c.Header(middleware.ETagHeader, middleware.ETag(cat.Version))
 	c.JSON(http.StatusOK, cat)
 }
 
-func CreateCat(c *gin.Context) {
+func (h *Handler) CreateCat(c *gin.Context) {
 	var cat store.Cat
-	if err := c.ShouldBindJSON(&cat); err != nil {
-		c.JSON(http.StatusUnprocessableEntity, newErrorResponse("Could not parse request data"))
+	if !h.bindJSON(c, &cat) {
 		return
 	}
 
-	exists, err := breed.ValidateCatBreed(cat.Breed)
+	exists, err := h.Breed.Validate(c.Request.Context(), cat.Breed)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, newErrorResponse("Could not validate breed"))
+		h.logError(c, err, "failed to validate breed")
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.BreedCheckFailed, "Could not validate breed"))
 		return
 	}
 
 	if !exists {
-		c.JSON(http.StatusBadRequest, newErrorResponse("Invalid breed"))
+		problem.Abort(c, problem.New(http.StatusUnprocessableEntity, problem.ValidationFailed, "Request data is invalid").
+			WithFields(problem.FieldError{Field: "breed", Message: "must be a known breed"}))
 		return
 	}
 
-	if err := application.App.Store.Cat.Create(c.Request.Context(), &cat); err != nil {
-		c.JSON(http.StatusInternalServerError, newErrorResponse("Could not create cat"))
+	if err := h.Store.Cat.Create(c.Request.Context(), &cat); err != nil {
+		h.logError(c, err, "failed to create cat")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
 		return
 	}
+	c.Header(middleware.ETagHeader, middleware.ETag(cat.Version))
 	c.JSON(http.StatusCreated, cat)
 }
 
-func UpdateCat(c *gin.Context) {
+func (h *Handler) UpdateCat(c *gin.Context) {
 	var request requestChangeSalary
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusUnprocessableEntity, newErrorResponse("Could not parse request data"))
+	if !h.bindJSON(c, &request) {
 		return
 	}
 
 	cat := store.Cat{
-		ID:     c.GetInt64("catID"),
-		Salary: request.Salary,
+		ID:      c.GetInt64("catID"),
+		Salary:  *request.Salary,
+		Version: middleware.IfMatch(c),
 	}
 
-	if err := application.App.Store.Cat.Update(c.Request.Context(), &cat); err != nil {
-		status := http.StatusInternalServerError
-		message := "Could not update cat"
+	if err := h.Store.Cat.Update(c.Request.Context(), &cat); err != nil {
+		h.logError(c, err, "failed to update cat")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
+		return
+	}
+	c.Header(middleware.ETagHeader, middleware.ETag(cat.Version))
+	c.JSON(http.StatusOK, gin.H{"message": "updated"})
+}
 
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Cat not found"
-		}
+type requestDeleteCat struct {
+	// ReassignTo is the idle cat taking over the active mission, a cat on
+	// a mission can't be fired without one.
+	ReassignTo int64 `form:"reassign_to"`
+}
 
-		c.JSON(status, newErrorResponse(message))
+func (h *Handler) DeleteCat(c *gin.Context) {
+	var request requestDeleteCat
+	if err := c.ShouldBindQuery(&request); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Could not parse query parameters"))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "updated"})
+
+	if request.ReassignTo < 0 {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Invalid query parameters").
+			WithFields(problem.FieldError{Field: "reassign_to", Message: "must be positive"}))
+		return
+	}
+
+	if err := h.Store.Cat.Retire(c.Request.Context(), c.GetInt64("catID"), middleware.IfMatch(c), request.ReassignTo); err != nil {
+		h.logError(c, err, "failed to delete cat")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+func (h *Handler) RestoreCat(c *gin.Context) {
+	cat, err := h.Store.Cat.Restore(c.Request.Context(), c.GetInt64("catID"))
+	if err != nil {
+		h.logError(c, err, "failed to restore cat")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
+		return
+	}
+	c.Header(middleware.ETagHeader, middleware.ETag(cat.Version))
+	c.JSON(http.StatusOK, cat)
 }
 
-func DeleteCat(c *gin.Context) {
-	if err := application.App.Store.Cat.Delete(c.Request.Context(), c.GetInt64("catID")); err != nil {
-		status := http.StatusInternalServerError
-		message := "Could not delete cat"
+func (h *Handler) GetCatSalaryHistory(c *gin.Context) {
+	history, err := h.Store.Cat.SalaryHistory(c.Request.Context(), c.GetInt64("catID"))
+	if err != nil {
+		h.logError(c, err, "failed to get cat salary history")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+type requestPayroll struct {
+	From string `form:"from"`
+	To   string `form:"to"`
+}
+
+type responsePayroll struct {
+	From  string              `json:"from"`
+	To    string              `json:"to"`
+	Total float64             `json:"total"`
+	Lines []store.PayrollLine `json:"lines"`
+}
 
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Cat not found"
+// GetPayroll reports the monthly payroll by breed and experience bracket,
+// from and to are months like 2024-01 and both are included.
+func (h *Handler) GetPayroll(c *gin.Context) {
+	var request requestPayroll
+	if err := c.ShouldBindQuery(&request); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Could not parse query parameters"))
+		return
+	}
+
+	var fields []problem.FieldError
+	month := func(field, raw string) time.Time {
+		t, err := time.Parse(store.PayrollMonthLayout, raw)
+		if err != nil {
+			fields = append(fields, problem.FieldError{Field: field, Message: "must be a month like 2024-01"})
 		}
+		return t
+	}
+	from, to := month("from", request.From), month("to", request.To)
+
+	if len(fields) == 0 {
+		months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+		switch {
+		case months < 1:
+			fields = append(fields, problem.FieldError{Field: "to", Message: "must not be before from"})
+		case months > store.MaxPayrollMonths:
+			fields = append(fields, problem.FieldError{Field: "to", Message: fmt.Sprintf("the period can't exceed %d months", store.MaxPayrollMonths)})
+		}
+	}
 
-		c.JSON(status, newErrorResponse(message))
+	if len(fields) > 0 {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Invalid query parameters").WithFields(fields...))
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+
+	lines, err := h.Store.Cat.Payroll(c.Request.Context(), from, to)
+	if err != nil {
+		h.logError(c, err, "failed to compute payroll")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
+		return
+	}
+
+	var total float64
+	for _, line := range lines {
+		total += line.Total
+	}
+
+	c.JSON(http.StatusOK, responsePayroll{
+		From:  request.From,
+		To:    request.To,
+		Total: math.Round(total*100) / 100,
+		Lines: lines,
+	})
 }
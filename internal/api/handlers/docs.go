@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"maps"
+	"net/http"
+	"spy-cat-agency/internal/api/middleware"
+	"spy-cat-agency/internal/api/openapi"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/health"
+	"spy-cat-agency/internal/store"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	tagCats     = "cats"
+	tagMissions = "missions"
+	tagTargets  = "targets"
+	tagNotes    = "notes"
+	tagDocs     = "docs"
+	tagOps      = "ops"
+)
+
+var problemResponse = openapi.Response{Body: problem.Problem{}, ContentType: problem.ContentType}
+
+// requestIfMatch is the header middleware.RequireIfMatch reads, operations
+// that take it also answer 412 and 428.
+type requestIfMatch struct {
+	IfMatch string `header:"If-Match" binding:"required"`
+}
+
+var etag = []string{middleware.ETagHeader}
+
+// requestIdempotencyKey is the header middleware.Idempotent reads,
+// operations that take it also answer 409 and 422.
+type requestIdempotencyKey struct {
+	IdempotencyKey string `header:"Idempotency-Key"`
+}
+
+// Docs describes every route mounted by api.Mount, keyed by openapi.Key.
+// A route without an entry here fails the api package tests.
+var Docs = map[string]openapi.Operation{
+	openapi.Key(http.MethodGet, "/v1/cats/"): {
+		Summary: "List cats page by page",
+		Tag:     tagCats,
+		Query:   requestListCats{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Page[store.Cat]{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodGet, "/v1/cats/:catID"): {
+		Summary: "Get a cat",
+		Tag:     tagCats,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Cat{}, Headers: etag},
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPost, "/v1/cats/"): {
+		Summary: "Hire a cat",
+		Tag:     tagCats,
+		Header:  requestIdempotencyKey{},
+		Body:    store.Cat{},
+		Responses: map[int]openapi.Response{
+			http.StatusCreated:             {Body: store.Cat{}, Headers: etag},
+			http.StatusUnprocessableEntity: problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPut, "/v1/cats/:catID"): {
+		Summary: "Change cat salary",
+		Tag:     tagCats,
+		Header:  requestIfMatch{},
+		Body:    requestChangeSalary{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: gin.H{}, Headers: etag},
+			http.StatusNotFound:            problemResponse,
+			http.StatusUnprocessableEntity: problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodDelete, "/v1/cats/:catID"): {
+		Summary: "Fire a cat, handing its active mission over to reassign_to",
+		Tag:     tagCats,
+		Header:  requestIfMatch{},
+		Query:   requestDeleteCat{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: gin.H{}},
+			http.StatusBadRequest:          {Description: "Invalid reassign_to or the cat taking over is busy", Body: problem.Problem{}, ContentType: problem.ContentType},
+			http.StatusNotFound:            problemResponse,
+			http.StatusConflict:            {Description: "The cat is on an active mission and nobody takes over", Body: problem.Problem{}, ContentType: problem.ContentType},
+			http.StatusUnprocessableEntity: {Description: "The cat taking over is the fired one or doesn't exist", Body: problem.Problem{}, ContentType: problem.ContentType},
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPost, "/v1/cats/:catID/restore"): {
+		Summary: "Bring back a fired cat, its missions stay as they are",
+		Tag:     tagCats,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Cat{}, Headers: etag},
+			http.StatusNotFound:            problemResponse,
+			http.StatusConflict:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodGet, "/v1/cats/:catID/salary-history"): {
+		Summary: "List the salary changes of a cat, oldest first",
+		Tag:     tagCats,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: []store.SalaryChange{}},
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodGet, "/v1/payroll"): {
+		Summary: "Report the monthly payroll by breed and experience bracket",
+		Tag:     tagCats,
+		Query:   requestPayroll{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: responsePayroll{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodGet, "/v1/audit"): {
+		Summary: "List the audit log of changes page by page, newest first",
+		Tag:     tagOps,
+		Query:   requestListAudit{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Page[store.AuditEntry]{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+
+	openapi.Key(http.MethodGet, "/v1/missions/"): {
+		Summary: "List missions with targets page by page",
+		Tag:     tagMissions,
+		Query:   requestListMissions{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Page[store.Mission]{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPost, "/v1/missions/"): {
+		Summary: "Create a draft mission with its targets",
+		Tag:     tagMissions,
+		Header:  requestIdempotencyKey{},
+		Body:    store.Mission{},
+		Responses: map[int]openapi.Response{
+			http.StatusCreated:             {Body: store.Mission{}, Headers: etag},
+			http.StatusUnprocessableEntity: problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodGet, "/v1/missions/:missionID"): {
+		Summary: "Get a mission with targets",
+		Tag:     tagMissions,
+		Query:   requestGetMission{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Mission{}, Headers: etag},
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodDelete, "/v1/missions/:missionID"): {
+		Summary: "Delete a mission without a spy",
+		Tag:     tagMissions,
+		Header:  requestIfMatch{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: response{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPut, "/v1/missions/:missionID/:catID/assign"): {
+		Summary: "Assign a cat to a draft mission",
+		Tag:     tagMissions,
+		Header:  requestIfMatch{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: response{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusNotFound:            problemResponse,
+			http.StatusConflict:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPost, "/v1/missions/:missionID/start"): {
+		Summary:   "Start an assigned mission",
+		Tag:       tagMissions,
+		Responses: transitionResponses,
+	},
+	openapi.Key(http.MethodPost, "/v1/missions/:missionID/complete"): {
+		Summary:   "Complete a mission once all targets are complete",
+		Tag:       tagMissions,
+		Responses: transitionResponses,
+	},
+	openapi.Key(http.MethodPost, "/v1/missions/:missionID/abort"): {
+		Summary:   "Abort a mission that is not finished",
+		Tag:       tagMissions,
+		Responses: transitionResponses,
+	},
+	openapi.Key(http.MethodPost, "/v1/missions/:missionID/restore"): {
+		Summary: "Bring back a deleted mission with its targets and notes",
+		Tag:     tagMissions,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: response{}, Headers: etag},
+			http.StatusNotFound:            problemResponse,
+			http.StatusConflict:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+
+	openapi.Key(http.MethodPost, "/v1/missions/targets/:missionID"): {
+		Summary: "Add a target to a mission",
+		Tag:     tagTargets,
+		Header:  requestIdempotencyKey{},
+		Body:    store.Target{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: response{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusNotFound:            problemResponse,
+			http.StatusUnprocessableEntity: problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodGet, "/v1/missions/targets/:targetID"): {
+		Summary: "Get a target",
+		Tag:     tagTargets,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Target{}, Headers: etag},
+			http.StatusForbidden:           problemResponse,
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPut, "/v1/missions/targets/:targetID"): {
+		Summary: "Mark a target complete",
+		Tag:     tagTargets,
+		Header:  requestIfMatch{},
+		Body:    requestMissionComplete{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: response{}, Headers: etag},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusNotFound:            problemResponse,
+			http.StatusUnprocessableEntity: problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodDelete, "/v1/missions/targets/:targetID"): {
+		Summary: "Delete an incomplete target",
+		Tag:     tagTargets,
+		Header:  requestIfMatch{},
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: response{}},
+			http.StatusBadRequest:          problemResponse,
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+
+	openapi.Key(http.MethodGet, "/v1/missions/targets/note/:targetID"): {
+		Summary: "List target notes",
+		Tag:     tagNotes,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: []store.Note{}},
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPost, "/v1/missions/targets/note/:targetID"): {
+		Summary:   "Add a note on a target",
+		Tag:       tagNotes,
+		Header:    requestIdempotencyKey{},
+		Body:      store.Note{},
+		Responses: noteChangeResponses,
+	},
+	openapi.Key(http.MethodGet, "/v1/missions/targets/note/:targetID/:noteID"): {
+		Summary: "Get a note",
+		Tag:     tagNotes,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                  {Body: store.Note{}},
+			http.StatusNotFound:            problemResponse,
+			http.StatusInternalServerError: problemResponse,
+		},
+	},
+	openapi.Key(http.MethodPut, "/v1/missions/targets/note/:targetID/:noteID"): {
+		Summary:   "Edit a note",
+		Tag:       tagNotes,
+		Body:      requestNote{},
+		Responses: noteChangeResponses,
+	},
+	openapi.Key(http.MethodDelete, "/v1/missions/targets/note/:targetID/:noteID"): {
+		Summary:   "Delete a note",
+		Tag:       tagNotes,
+		Responses: noteChangeResponses,
+	},
+
+	openapi.Key(http.MethodGet, "/v1/openapi.json"): {
+		Summary: "This document",
+		Tag:     tagDocs,
+		Public:  true,
+		Responses: map[int]openapi.Response{
+			http.StatusOK: {Description: "OpenAPI 3 document"},
+		},
+	},
+	openapi.Key(http.MethodGet, "/v1/docs"): {
+		Summary: "Human readable API docs",
+		Tag:     tagDocs,
+		Public:  true,
+		Responses: map[int]openapi.Response{
+			http.StatusOK: {Description: "HTML page"},
+		},
+	},
+	openapi.Key(http.MethodGet, "/metrics"): {
+		Summary: "Prometheus metrics",
+		Tag:     tagOps,
+		Public:  true,
+		Responses: map[int]openapi.Response{
+			http.StatusOK: {Description: "Prometheus text format", ContentType: "text/plain"},
+		},
+	},
+	openapi.Key(http.MethodGet, "/healthz"): {
+		Summary: "Liveness probe, the process is up",
+		Tag:     tagOps,
+		Public:  true,
+		Responses: map[int]openapi.Response{
+			http.StatusOK: {Body: response{}},
+		},
+	},
+	openapi.Key(http.MethodGet, "/readyz"): {
+		Summary: "Readiness probe, the database, schema and breed validator are usable and the server isn't shutting down",
+		Tag:     tagOps,
+		Public:  true,
+		Responses: map[int]openapi.Response{
+			http.StatusOK:                 {Body: health.Report{}},
+			http.StatusServiceUnavailable: {Body: health.Report{}},
+		},
+	},
+	openapi.Key(http.MethodGet, "/version"): {
+		Summary: "Build info and applied schema version",
+		Tag:     tagOps,
+		Public:  true,
+		Responses: map[int]openapi.Response{
+			http.StatusOK: {Body: responseVersion{}},
+		},
+	},
+}
+
+var transitionResponses = map[int]openapi.Response{
+	http.StatusOK:                  {Body: response{}, Headers: etag},
+	http.StatusBadRequest:          problemResponse,
+	http.StatusNotFound:            problemResponse,
+	http.StatusConflict:            {Description: "Transition is not allowed from the current status", Body: problem.Problem{}, ContentType: problem.ContentType},
+	http.StatusInternalServerError: problemResponse,
+}
+
+var noteChangeResponses = map[int]openapi.Response{
+	http.StatusOK:                  {Body: response{}},
+	http.StatusBadRequest:          {Description: "Target or mission is finished, notes are frozen", Body: problem.Problem{}, ContentType: problem.ContentType},
+	http.StatusNotFound:            problemResponse,
+	http.StatusUnprocessableEntity: problemResponse,
+	http.StatusInternalServerError: problemResponse,
+}
+
+var securitySchemes = map[string]openapi.SecurityScheme{
+	"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+	"apiKeyAuth": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+}
+
+// withPreconditionResponses adds the 412 and 428 answers of
+// middleware.RequireIfMatch to the operations taking If-Match.
+func withPreconditionResponses(ops map[string]openapi.Operation) map[string]openapi.Operation {
+	conditional := make(map[string]openapi.Operation, len(ops))
+	for key, op := range ops {
+		if _, ok := op.Header.(requestIfMatch); ok {
+			responses := maps.Clone(op.Responses)
+			responses[http.StatusPreconditionFailed] = openapi.Response{Description: "If-Match is not the current ETag", Body: problem.Problem{}, ContentType: problem.ContentType}
+			responses[http.StatusPreconditionRequired] = openapi.Response{Description: "If-Match is missing", Body: problem.Problem{}, ContentType: problem.ContentType}
+			op.Responses = responses
+		}
+		conditional[key] = op
+	}
+	return conditional
+}
+
+// withIdempotencyResponses adds the 409 and 422 answers of
+// middleware.Idempotent to the operations taking Idempotency-Key.
+func withIdempotencyResponses(ops map[string]openapi.Operation) map[string]openapi.Operation {
+	retriable := make(map[string]openapi.Operation, len(ops))
+	for key, op := range ops {
+		if _, ok := op.Header.(requestIdempotencyKey); ok {
+			responses := maps.Clone(op.Responses)
+			responses[http.StatusConflict] = openapi.Response{Description: "A request with the Idempotency-Key is still being served", Body: problem.Problem{}, ContentType: problem.ContentType}
+			responses[http.StatusUnprocessableEntity] = openapi.Response{Description: "Invalid body, or the Idempotency-Key was used for another request", Body: problem.Problem{}, ContentType: problem.ContentType}
+			op.Responses = responses
+		}
+		retriable[key] = op
+	}
+	return retriable
+}
+
+// withAuthResponses adds the 401 and 403 answers of the auth middleware to
+// every non public operation.
+func withAuthResponses(ops map[string]openapi.Operation) map[string]openapi.Operation {
+	secured := make(map[string]openapi.Operation, len(ops))
+	for key, op := range ops {
+		if !op.Public {
+			responses := maps.Clone(op.Responses)
+			responses[http.StatusUnauthorized] = openapi.Response{Description: "Missing or invalid credentials", Body: problem.Problem{}, ContentType: problem.ContentType}
+			responses[http.StatusForbidden] = openapi.Response{Description: "Not allowed for the caller", Body: problem.Problem{}, ContentType: problem.ContentType}
+			op.Responses = responses
+		}
+		secured[key] = op
+	}
+	return secured
+}
+
+// OpenAPISpec serves the document for every documented route of router. It
+// is built on first request, when all routes are registered.
+func OpenAPISpec(router *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var doc *openapi.Document
+
+	return func(c *gin.Context) {
+		once.Do(func() {
+			info := openapi.Info{Title: "Spy Cat Agency", Version: "1.0.0"}
+			doc = openapi.Build(info, router.Routes(), withAuthResponses(withPreconditionResponses(withIdempotencyResponses(Docs))))
+			doc.Secure(securitySchemes)
+		})
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Spy Cat Agency API</title>
+	<meta charset="utf-8"/>
+</head>
+<body>
+	<redoc spec-url="/v1/openapi.json"></redoc>
+	<script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>`
+
+func DocsPage(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log/slog"
+	"spy-cat-agency/internal/api/middleware"
+	"spy-cat-agency/internal/auth"
+	"spy-cat-agency/internal/breed"
+	"spy-cat-agency/internal/health"
+	"spy-cat-agency/internal/metrics"
+	"spy-cat-agency/internal/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the API routes. It holds everything the routes need, so
+// several servers with different stores can run in one process.
+type Handler struct {
+	Store  store.Storage
+	Breed  breed.Validator
+	Auth   *auth.Authenticator
+	Logger *slog.Logger
+	// Metrics collects what /metrics exports.
+	Metrics *metrics.Metrics
+	// Health answers the readiness probe.
+	Health *health.Checker
+	// Now is the clock, time.Now outside of tests.
+	Now func() time.Time
+	// IdempotencyTTL is how long responses are kept for Idempotency-Key
+	// replays.
+	IdempotencyTTL time.Duration
+}
+
+// DefaultIdempotencyTTL is the IdempotencyTTL New sets.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// New creates a handler, a nil logger, metrics, checker or clock falls back to
+// the standard logger, a fresh registry, a checker without checks and time.Now.
+func New(storage store.Storage, breedValidator breed.Validator, authenticator *auth.Authenticator, logger *slog.Logger, m *metrics.Metrics, checker *health.Checker, now func() time.Time) *Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if m == nil {
+		m = metrics.New()
+	}
+	if checker == nil {
+		checker = health.New(time.Second)
+	}
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Handler{
+		Store:   storage,
+		Breed:   breedValidator,
+		Auth:    authenticator,
+		Logger:  logger,
+		Metrics: m,
+		Health:  checker,
+		Now:     now,
+
+		IdempotencyTTL: DefaultIdempotencyTTL,
+	}
+}
+
+// logError logs err with the id of the request that hit it.
+func (h *Handler) logError(c *gin.Context, err error, message string) {
+	middleware.Log(c, h.Logger).Error(message, "error", err)
+}
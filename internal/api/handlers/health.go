@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"spy-cat-agency/internal/buildinfo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz answers as long as the process serves requests, it checks nothing
+// else so a database outage doesn't get the process restarted.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, response{Message: "ok"})
+}
+
+// Readyz tells whether the server should get traffic.
+func (h *Handler) Readyz(c *gin.Context) {
+	report := h.Health.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
+
+type responseVersion struct {
+	buildinfo.Info
+	// SchemaVersion is null when the store has no schema or it can't be read.
+	SchemaVersion *uint `json:"schema_version"`
+}
+
+func (h *Handler) Version(c *gin.Context) {
+	res := responseVersion{Info: buildinfo.Get()}
+
+	if h.Health.Schema != nil {
+		version, err := h.Health.Schema(c.Request.Context())
+		if err != nil {
+			h.logError(c, err, "failed to read schema version")
+		} else {
+			res.SchemaVersion = &version
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
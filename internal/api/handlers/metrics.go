@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"spy-cat-agency/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics serves m in the Prometheus text format.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	handler := m.Handler()
+	return func(c *gin.Context) {
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -1,17 +1,28 @@
 package handlers
 
 import (
-	"errors"
-	"log"
+	"fmt"
 	"net/http"
-	"spy-cat-agency/internal/application"
+	"spy-cat-agency/internal/api/middleware"
+	"spy-cat-agency/internal/api/problem"
 	"spy-cat-agency/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
 
 type requestMissionComplete struct {
-	IsComplete bool `json:"is_complete" validate:"required"`
+	IsComplete *bool `json:"is_complete" validate:"required"`
+}
+
+type requestListMissions struct {
+	Cursor         string `form:"cursor"`
+	Limit          int    `form:"limit"`
+	IncludeDeleted bool   `form:"include_deleted"`
+}
+
+type requestGetMission struct {
+	// Include embeds target notes when set to "notes".
+	Include string `form:"include"`
 }
 
 type response struct {
@@ -26,358 +37,199 @@ func newResponse(message string, data ...interface{}) response {
 	return response{Message: message}
 }
 
-func logError(err error, message string) {
-	log.Printf("ERROR: %s: %v", message, err)
-}
-
-func GetAllMissions(c *gin.Context) {
-	missions, err := application.App.Store.Mission.GetAllWithTargets(c.Request.Context())
-	if err != nil {
-		logError(err, "failed to get all missions")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not get all missions"))
+func (h *Handler) GetAllMissions(c *gin.Context) {
+	var request requestListMissions
+	if err := c.ShouldBindQuery(&request); err != nil {
+		h.logError(c, err, "failed to parse missions query")
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Could not parse query parameters"))
 		return
 	}
-	c.JSON(http.StatusOK, missions)
-}
 
-func GetMissionByID(c *gin.Context) {
-	mission, err := application.App.Store.Mission.GetByIDWithTargets(c.Request.Context(), c.GetInt64("missionID"))
-	if err != nil {
-		logError(err, "failed to get mission by ID")
-		status := http.StatusInternalServerError
-		message := "Could not get mission"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Mission not found"
-		}
-
-		c.JSON(status, newResponse(message))
+	if request.Limit < 0 || request.Limit > store.MaxPageLimit {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Invalid query parameters").
+			WithFields(problem.FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", store.MaxPageLimit)}))
 		return
 	}
-	c.JSON(http.StatusOK, mission)
-}
 
-func CreateMission(c *gin.Context) {
-	var mission store.Mission
-	if err := c.ShouldBindJSON(&mission); err != nil {
-		logError(err, "failed to parse mission data")
-		c.JSON(http.StatusUnprocessableEntity, newResponse("Could not parse request data"))
-		return
+	filter := store.MissionFilter{
+		Cursor:         request.Cursor,
+		Limit:          request.Limit,
+		IncludeDeleted: request.IncludeDeleted,
 	}
 
-	if err := application.App.Store.Mission.Create(c.Request.Context(), &mission); err != nil {
-		logError(err, "failed to create mission")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not create mission"))
+	page, err := h.Store.Mission.ListWithTargets(c.Request.Context(), filter)
+	if err != nil {
+		h.logError(c, err, "failed to get all missions")
+		problem.Abort(c, problem.From(err, problem.MissionNotFound))
 		return
 	}
-	c.JSON(http.StatusCreated, mission)
+	c.JSON(http.StatusOK, page)
 }
 
-func UpdateMission(c *gin.Context) {
-	var request requestMissionComplete
-	if err := c.ShouldBindJSON(&request); err != nil {
-		logError(err, "failed to parse mission update data")
-		c.JSON(http.StatusUnprocessableEntity, newResponse("Could not parse request data"))
-		return
-	}
-
+func (h *Handler) GetMissionByID(c *gin.Context) {
 	ctx := c.Request.Context()
 	missionID := c.GetInt64("missionID")
 
-	targets, err := application.App.Store.Mission.GetAllMissionTargets(ctx, missionID)
-	if err != nil {
-		logError(err, "failed to get mission targets")
-		status := http.StatusInternalServerError
-		message := "Could not get targets"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Targets not found"
-		}
-
-		c.JSON(status, newResponse(message))
+	var request requestGetMission
+	if err := c.ShouldBindQuery(&request); err != nil {
+		h.logError(c, err, "failed to parse mission query")
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidQuery, "Could not parse query parameters"))
 		return
 	}
 
-	for _, t := range targets {
-		if !t.IsComplete {
-			c.JSON(http.StatusBadRequest, newResponse("All targets must be completed first"))
-			return
-		}
-	}
-
-	mission := store.Mission{
-		ID:         missionID,
-		IsComplete: request.IsComplete,
+	var mission *store.Mission
+	var err error
+	if request.Include == "notes" {
+		mission, err = h.Store.Mission.GetByIDWithNotes(ctx, missionID)
+	} else {
+		mission, err = h.Store.Mission.GetByIDWithTargets(ctx, missionID)
 	}
-
-	if err := application.App.Store.Mission.Update(ctx, &mission); err != nil {
-		logError(err, "failed to update mission")
-		status := http.StatusInternalServerError
-		message := "Could not update mission"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Mission not found"
-		}
-
-		c.JSON(status, newResponse(message))
-		return
-	}
-	c.JSON(http.StatusOK, newResponse("Mission updated"))
-}
-
-func DeleteMission(c *gin.Context) {
-	ctx := c.Request.Context()
-	missionID := c.GetInt64("missionID")
-
-	mission, err := application.App.Store.Mission.GetByID(ctx, missionID)
 	if err != nil {
-		logError(err, "failed to get mission for deletion")
-		status := http.StatusInternalServerError
-		message := "Could not get mission"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Mission not found"
-		}
-
-		c.JSON(status, newResponse(message))
+		h.logError(c, err, "failed to get mission by ID")
+		problem.Abort(c, problem.From(err, problem.MissionNotFound))
 		return
 	}
+	c.Header(middleware.ETagHeader, middleware.ETag(mission.Version))
+	c.JSON(http.StatusOK, mission)
+}
 
-	if mission.CatID != nil {
-		c.JSON(http.StatusBadRequest, newResponse("Cannot delete mission: spy already assigned"))
+func (h *Handler) CreateMission(c *gin.Context) {
+	var mission store.Mission
+	if !h.bindJSON(c, &mission) {
 		return
 	}
 
-	if err := application.App.Store.Mission.Delete(ctx, missionID); err != nil {
-		logError(err, "failed to delete mission")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not delete mission"))
+	if err := h.Store.Mission.Create(c.Request.Context(), &mission); err != nil {
+		h.logError(c, err, "failed to create mission")
+		problem.Abort(c, problem.From(err, problem.MissionNotFound))
 		return
 	}
-	c.JSON(http.StatusOK, newResponse("Mission deleted"))
+	c.Header(middleware.ETagHeader, middleware.ETag(mission.Version))
+	c.JSON(http.StatusCreated, mission)
 }
 
-func AssignCatForMission(c *gin.Context) {
-	ctx := c.Request.Context()
-	missionID := c.GetInt64("missionID")
-	catID := c.GetInt64("catID")
-
-	cat, err := application.App.Store.Cat.GetByID(ctx, catID)
-	if err != nil {
-		logError(err, "failed to get cat for mission assignment")
-		status := http.StatusInternalServerError
-		message := "Could not get cat"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Cat not found"
-		}
-
-		c.JSON(status, newResponse(message))
-		return
-	}
-
-	hasMissions, err := application.App.Store.Cat.HasIncompleteMission(ctx, catID)
-	if err != nil {
-		logError(err, "failed to check cat's incomplete missions")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not check cat missions"))
-		return
-	}
+func (h *Handler) StartMission(c *gin.Context) {
+	h.transitionMission(c, store.MissionInProgress, "Mission started")
+}
 
-	if hasMissions {
-		c.JSON(http.StatusBadRequest, newResponse("Cannot assign mission: spy has unfinished business"))
-		return
-	}
+func (h *Handler) CompleteMission(c *gin.Context) {
+	h.transitionMission(c, store.MissionCompleted, "Mission completed")
+}
 
-	hasSpy, err := application.App.Store.Mission.HasAssignedSpy(ctx, missionID)
-	if err != nil {
-		logError(err, "failed to check mission's assigned spy")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not check mission assignment"))
-		return
-	}
+func (h *Handler) AbortMission(c *gin.Context) {
+	h.transitionMission(c, store.MissionAborted, "Mission aborted")
+}
 
-	if hasSpy {
-		c.JSON(http.StatusBadRequest, newResponse("Mission already has an assigned spy"))
-		return
+func (h *Handler) transitionMission(c *gin.Context, next store.MissionStatus, done string) {
+	mission := store.Mission{
+		ID:     c.GetInt64("missionID"),
+		Status: next,
 	}
 
-	if err := application.App.Store.Mission.AssignCat(ctx, cat.ID, missionID); err != nil {
-		logError(err, "failed to assign cat to mission")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not assign mission"))
+	if err := h.Store.Mission.Update(c.Request.Context(), &mission); err != nil {
+		h.logError(c, err, "failed to change mission status")
+		p := problem.From(err, problem.MissionNotFound)
+		if p.Code == problem.InvalidTransition {
+			p.Detail = fmt.Sprintf("Mission cannot move to %s from its current status", next)
+		}
+		problem.Abort(c, p)
 		return
 	}
-	c.JSON(http.StatusOK, newResponse("Mission assigned", cat))
+	c.Header(middleware.ETagHeader, middleware.ETag(mission.Version))
+	c.JSON(http.StatusOK, newResponse(done, mission))
 }
 
-func AddMissionTarget(c *gin.Context) {
-	var target store.Target
-	if err := c.ShouldBindJSON(&target); err != nil {
-		logError(err, "failed to parse target data")
-		c.JSON(http.StatusUnprocessableEntity, newResponse("Could not parse request data"))
+func (h *Handler) DeleteMission(c *gin.Context) {
+	if err := h.Store.Mission.Delete(c.Request.Context(), c.GetInt64("missionID"), middleware.IfMatch(c)); err != nil {
+		h.logError(c, err, "failed to delete mission")
+		problem.Abort(c, problem.From(err, problem.MissionNotFound))
 		return
 	}
+	c.JSON(http.StatusOK, newResponse("Mission deleted"))
+}
 
-	ctx := c.Request.Context()
-	missionID := c.GetInt64("missionID")
-
-	count, err := application.App.Store.Mission.GetTargetsQuantity(ctx, missionID)
+func (h *Handler) RestoreMission(c *gin.Context) {
+	mission, err := h.Store.Mission.Restore(c.Request.Context(), c.GetInt64("missionID"))
 	if err != nil {
-		logError(err, "failed to get targets count")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not get targets count"))
+		h.logError(c, err, "failed to restore mission")
+		problem.Abort(c, problem.From(err, problem.MissionNotFound))
 		return
 	}
-
-	if count >= 3 {
-		c.JSON(http.StatusBadRequest, newResponse("Maximum number of targets (3) reached"))
-		return
-	}
-
-	if err := application.App.Store.Mission.AddTarget(ctx, missionID, &target); err != nil {
-		logError(err, "failed to add target to mission")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not add target"))
-		return
-	}
-	c.JSON(http.StatusOK, newResponse("Target added"))
+	c.Header(middleware.ETagHeader, middleware.ETag(mission.Version))
+	c.JSON(http.StatusOK, newResponse("Mission restored", mission))
 }
 
-func DeleteMissionTarget(c *gin.Context) {
+func (h *Handler) AssignCatForMission(c *gin.Context) {
 	ctx := c.Request.Context()
 	missionID := c.GetInt64("missionID")
-	targetID := c.GetInt64("targetID")
+	catID := c.GetInt64("catID")
 
-	target, err := application.App.Store.Mission.GetTargetByID(ctx, targetID)
+	cat, err := h.Store.Cat.GetByID(ctx, catID)
 	if err != nil {
-		logError(err, "failed to get target for deletion")
-		status := http.StatusInternalServerError
-		message := "Could not get target"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Target not found"
-		}
-
-		c.JSON(status, newResponse(message))
+		h.logError(c, err, "failed to get cat for mission assignment")
+		problem.Abort(c, problem.From(err, problem.CatNotFound))
 		return
 	}
 
-	if target.IsComplete {
-		c.JSON(http.StatusBadRequest, newResponse("Cannot delete completed target"))
+	if err := h.Store.Mission.AssignCat(ctx, cat.ID, missionID, middleware.IfMatch(c)); err != nil {
+		h.logError(c, err, "failed to assign cat to mission")
+		problem.Abort(c, problem.From(err, problem.MissionNotFound))
 		return
 	}
+	c.JSON(http.StatusOK, newResponse("Mission assigned", cat))
+}
 
-	count, err := application.App.Store.Mission.GetTargetsQuantity(ctx, missionID)
-	if err != nil {
-		logError(err, "failed to get targets count")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not get targets count"))
+func (h *Handler) AddMissionTarget(c *gin.Context) {
+	var target store.Target
+	if !h.bindJSON(c, &target) {
 		return
 	}
 
-	if count <= 1 {
-		c.JSON(http.StatusBadRequest, newResponse("Cannot delete last target"))
+	if err := h.Store.Mission.AddTarget(c.Request.Context(), c.GetInt64("missionID"), &target); err != nil {
+		h.logError(c, err, "failed to add target to mission")
+		problem.Abort(c, problem.From(err, problem.MissionNotFound))
 		return
 	}
+	c.JSON(http.StatusOK, newResponse("Target added", target))
+}
 
-	if err := application.App.Store.Mission.RemoveTarget(ctx, targetID); err != nil {
-		logError(err, "failed to delete target")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not delete target"))
+func (h *Handler) DeleteMissionTarget(c *gin.Context) {
+	if err := h.Store.Mission.RemoveTarget(c.Request.Context(), c.GetInt64("targetID"), middleware.IfMatch(c)); err != nil {
+		h.logError(c, err, "failed to delete target")
+		problem.Abort(c, problem.From(err, problem.TargetNotFound))
 		return
 	}
 	c.JSON(http.StatusOK, newResponse("Target deleted"))
 }
 
-func UpdateMissionTarget(c *gin.Context) {
-	ctx := c.Request.Context()
-	targetID := c.GetInt64("targetID")
-
-	target, err := application.App.Store.Mission.GetTargetByID(ctx, targetID)
+func (h *Handler) GetMissionTarget(c *gin.Context) {
+	target, err := h.Store.Mission.GetTargetByID(c.Request.Context(), c.GetInt64("targetID"))
 	if err != nil {
-		logError(err, "failed to get target for update")
-		status := http.StatusInternalServerError
-		message := "Could not get target"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Target not found"
-		}
-
-		c.JSON(status, newResponse(message))
-		return
-	}
-
-	if target.IsComplete {
-		c.JSON(http.StatusBadRequest, newResponse("Cannot update completed target"))
+		h.logError(c, err, "failed to get target by ID")
+		problem.Abort(c, problem.From(err, problem.TargetNotFound))
 		return
 	}
+	c.Header(middleware.ETagHeader, middleware.ETag(target.Version))
+	c.JSON(http.StatusOK, target)
+}
 
+func (h *Handler) UpdateMissionTarget(c *gin.Context) {
 	var req requestMissionComplete
-	if err := c.ShouldBindJSON(&req); err != nil {
-		logError(err, "failed to parse target data")
-		c.JSON(http.StatusUnprocessableEntity, newResponse("Could not parse request data"))
-		return
-	}
-
-	target.IsComplete = req.IsComplete
-
-	mission, err := application.App.Store.Mission.GetByID(ctx, target.MissionID)
-	if err != nil {
-		logError(err, "failed to get mission for target update")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not verify mission status"))
+	if !h.bindJSON(c, &req) {
 		return
 	}
 
-	if mission.CatID == nil {
-		c.JSON(http.StatusBadRequest, newResponse("Cannot update target: no spy assigned to mission"))
-		return
+	target := &store.Target{
+		ID:         c.GetInt64("targetID"),
+		IsComplete: *req.IsComplete,
+		Version:    middleware.IfMatch(c),
 	}
 
-	if err := application.App.Store.Mission.UpdateTarget(ctx, target); err != nil {
-		logError(err, "failed to update target")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not update target"))
+	if err := h.Store.Mission.UpdateTarget(c.Request.Context(), target); err != nil {
+		h.logError(c, err, "failed to update target")
+		problem.Abort(c, problem.From(err, problem.TargetNotFound))
 		return
 	}
+	c.Header(middleware.ETagHeader, middleware.ETag(target.Version))
 	c.JSON(http.StatusOK, newResponse("Target updated"))
 }
-
-func AddNoteOnTarget(c *gin.Context) {
-	ctx := c.Request.Context()
-	targetID := c.GetInt64("targetID")
-
-	target, err := application.App.Store.Mission.GetTargetByID(ctx, targetID)
-	if err != nil {
-		logError(err, "failed to get target for adding note")
-		status := http.StatusInternalServerError
-		message := "Could not get target"
-
-		if errors.Is(err, store.ErrorNotFound) {
-			status = http.StatusNotFound
-			message = "Target not found"
-		}
-
-		c.JSON(status, newResponse(message))
-		return
-	}
-
-	if target.IsComplete {
-		c.JSON(http.StatusBadRequest, newResponse("Cannot add note to completed target"))
-		return
-	}
-
-	var note store.Note
-	if err := c.ShouldBindJSON(&note); err != nil {
-		logError(err, "failed to parse note data")
-		c.JSON(http.StatusUnprocessableEntity, newResponse("Could not parse request data"))
-		return
-	}
-
-	note.TargetID = target.ID
-
-	if err := application.App.Store.Mission.AddNote(ctx, &note); err != nil {
-		logError(err, "failed to add note to target")
-		c.JSON(http.StatusInternalServerError, newResponse("Could not add note"))
-		return
-	}
-	c.JSON(http.StatusOK, newResponse("Note added"))
-}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type requestNote struct {
+	Note string `json:"note" validate:"notblank,max=2000"`
+}
+
+// getTargetNote loads the note and makes sure it belongs to the target from
+// the path. On failure the response is already written and false is returned.
+func (h *Handler) getTargetNote(c *gin.Context, targetID, noteID int64) (*store.Note, bool) {
+	note, err := h.Store.Mission.GetNoteByID(c.Request.Context(), noteID)
+	if err != nil {
+		h.logError(c, err, "failed to get note")
+		problem.Abort(c, problem.From(err, problem.NoteNotFound))
+		return nil, false
+	}
+
+	if note.TargetID != targetID {
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.NoteNotFound, "Note not found"))
+		return nil, false
+	}
+
+	return note, true
+}
+
+func (h *Handler) GetTargetNotes(c *gin.Context) {
+	ctx := c.Request.Context()
+	targetID := c.GetInt64("targetID")
+
+	if _, err := h.Store.Mission.GetTargetByID(ctx, targetID); err != nil {
+		h.logError(c, err, "failed to get target for notes")
+		problem.Abort(c, problem.From(err, problem.TargetNotFound))
+		return
+	}
+
+	notes, err := h.Store.Mission.GetAllTargetNotes(ctx, targetID)
+	if err != nil {
+		h.logError(c, err, "failed to get target notes")
+		problem.Abort(c, problem.From(err, problem.TargetNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, notes)
+}
+
+func (h *Handler) GetNoteByID(c *gin.Context) {
+	note, ok := h.getTargetNote(c, c.GetInt64("targetID"), c.GetInt64("noteID"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+func (h *Handler) AddNoteOnTarget(c *gin.Context) {
+	var note store.Note
+	if !h.bindJSON(c, &note) {
+		return
+	}
+
+	note.TargetID = c.GetInt64("targetID")
+
+	if err := h.Store.Mission.AddNote(c.Request.Context(), &note); err != nil {
+		h.logError(c, err, "failed to add note to target")
+		problem.Abort(c, problem.From(err, problem.TargetNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, newResponse("Note added", note))
+}
+
+func (h *Handler) UpdateNote(c *gin.Context) {
+	var request requestNote
+	if !h.bindJSON(c, &request) {
+		return
+	}
+
+	targetID := c.GetInt64("targetID")
+
+	note, ok := h.getTargetNote(c, targetID, c.GetInt64("noteID"))
+	if !ok {
+		return
+	}
+
+	note.Note = request.Note
+
+	if err := h.Store.Mission.UpdateNote(c.Request.Context(), note); err != nil {
+		h.logError(c, err, "failed to update note")
+		problem.Abort(c, problem.From(err, problem.NoteNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, newResponse("Note updated", note))
+}
+
+func (h *Handler) DeleteNote(c *gin.Context) {
+	targetID := c.GetInt64("targetID")
+
+	note, ok := h.getTargetNote(c, targetID, c.GetInt64("noteID"))
+	if !ok {
+		return
+	}
+
+	if err := h.Store.Mission.RemoveNote(c.Request.Context(), note.ID); err != nil {
+		h.logError(c, err, "failed to delete note")
+		problem.Abort(c, problem.From(err, problem.NoteNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, newResponse("Note deleted"))
+}
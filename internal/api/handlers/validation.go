@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"spy-cat-agency/internal/api/problem"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// validate runs the `validate` struct tags. gin only knows `binding` tags, so
+// payloads are checked explicitly by bindJSON.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// report fields by their json names, that's what clients send
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+
+	return v
+}
+
+// bindJSON decodes the body into obj and validates it. On failure the 422
+// problem is already written and false is returned.
+func (h *Handler) bindJSON(c *gin.Context, obj any) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		h.logError(c, err, "failed to parse request data")
+		problem.Abort(c, problem.New(http.StatusUnprocessableEntity, problem.InvalidBody, "Could not parse request data"))
+		return false
+	}
+
+	if err := validate.Struct(obj); err != nil {
+		var errs validator.ValidationErrors
+		if !errors.As(err, &errs) {
+			h.logError(c, err, "failed to validate request data")
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.InternalError, "Internal server error"))
+			return false
+		}
+
+		fields := make([]problem.FieldError, 0, len(errs))
+		for _, fe := range errs {
+			fields = append(fields, problem.FieldError{
+				Field:   fieldPath(fe),
+				Message: fieldMessage(fe),
+			})
+		}
+
+		problem.Abort(c, problem.New(http.StatusUnprocessableEntity, problem.ValidationFailed, "Request data is invalid").
+			WithFields(fields...))
+		return false
+	}
+
+	return true
+}
+
+// fieldPath drops the Go struct name from the namespace, "Mission.targets[0].name"
+// becomes "targets[0].name".
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	kind := fe.Kind()
+	isLength := kind == reflect.String
+	isCount := kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "min", "gte":
+		switch {
+		case isLength:
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		case isCount:
+			return fmt.Sprintf("must contain at least %s item(s)", fe.Param())
+		default:
+			return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+		}
+	case "max", "lte":
+		switch {
+		case isLength:
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		case isCount:
+			return fmt.Sprintf("must contain at most %s item(s)", fe.Param())
+		default:
+			return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+		}
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
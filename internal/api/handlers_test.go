@@ -0,0 +1,814 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"spy-cat-agency/internal/api"
+	"spy-cat-agency/internal/api/handlers"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/auth"
+	"spy-cat-agency/internal/breed"
+	"spy-cat-agency/internal/store"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const missingID = 999999
+
+const testSigningKey = "handler-suite-signing-key-0123456789"
+
+// fixture is one server on its own in-memory store, no network and no database.
+type fixture struct {
+	router *gin.Engine
+	store  store.Storage
+	auth   *auth.Authenticator
+}
+
+func newFixture(t *testing.T, hits *routeHits) *fixture {
+	t.Helper()
+
+	storage := store.NewMemoryStorage()
+
+	authenticator, err := auth.New([]byte(testSigningKey), map[string]string{"suite": testAPIKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	h := handlers.New(storage, breed.NewCatalog(breed.Names), authenticator, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, now)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(hits.record)
+	api.Mount(router, h)
+
+	return &fixture{router: router, store: storage, auth: authenticator}
+}
+
+// routeHits remembers which route patterns the suite has called.
+type routeHits struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (r *routeHits) record(c *gin.Context) {
+	c.Next()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen[c.Request.Method+" "+c.FullPath()] = true
+}
+
+// world is the data every case starts from.
+type world struct {
+	idleCat     *store.Cat
+	busyCat     *store.Cat
+	assignedCat *store.Cat
+	finishedCat *store.Cat
+
+	// draft has two targets and no spy, a note sits on its first target
+	draft *store.Mission
+	// active is in progress with busyCat, its first target is complete
+	active *store.Mission
+	// assigned belongs to assignedCat and has one open target
+	assigned *store.Mission
+	// finished was completed by finishedCat
+	finished *store.Mission
+	full     *store.Mission
+	single   *store.Mission
+
+	draftNote  *store.Note
+	activeNote *store.Note
+	frozenNote *store.Note
+}
+
+func seedWorld(t *testing.T, s store.Storage) *world {
+	t.Helper()
+	ctx := context.Background()
+	w := &world{}
+
+	cat := func(name string) *store.Cat {
+		c := &store.Cat{Name: name, YearsOfExperience: 2, Breed: "Siamese", Salary: 1000}
+		must(t, s.Cat.Create(ctx, c))
+		return c
+	}
+	mission := func(targets int) *store.Mission {
+		m := &store.Mission{}
+		for i := range targets {
+			m.Targets = append(m.Targets, store.Target{Name: fmt.Sprintf("target %d", i), Country: "Ukraine"})
+		}
+		must(t, s.Mission.Create(ctx, m))
+		return m
+	}
+	note := func(target store.Target, text string) *store.Note {
+		n := &store.Note{TargetID: target.ID, Note: text}
+		must(t, s.Mission.AddNote(ctx, n))
+		return n
+	}
+	complete := func(target store.Target) {
+		target.IsComplete = true
+		must(t, s.Mission.UpdateTarget(ctx, &target))
+	}
+	move := func(m *store.Mission, status store.MissionStatus) {
+		must(t, s.Mission.Update(ctx, &store.Mission{ID: m.ID, Status: status}))
+	}
+
+	w.idleCat = cat("Idle")
+	w.busyCat = cat("Busy")
+	w.assignedCat = cat("Assigned")
+	w.finishedCat = cat("Finished")
+
+	w.draft = mission(2)
+	w.draftNote = note(w.draft.Targets[0], "draft note")
+
+	w.active = mission(2)
+	must(t, s.Mission.AssignCat(ctx, w.busyCat.ID, w.active.ID, store.AnyVersion))
+	move(w.active, store.MissionInProgress)
+	w.frozenNote = note(w.active.Targets[0], "before completion")
+	complete(w.active.Targets[0])
+	w.activeNote = note(w.active.Targets[1], "still working")
+
+	w.assigned = mission(1)
+	must(t, s.Mission.AssignCat(ctx, w.assignedCat.ID, w.assigned.ID, store.AnyVersion))
+
+	w.finished = mission(1)
+	must(t, s.Mission.AssignCat(ctx, w.finishedCat.ID, w.finished.ID, store.AnyVersion))
+	move(w.finished, store.MissionInProgress)
+	complete(w.finished.Targets[0])
+	move(w.finished, store.MissionCompleted)
+
+	w.full = mission(store.MaxMissionTargets)
+	w.single = mission(1)
+
+	return w
+}
+
+// caller picks the credentials of a request.
+type caller int
+
+const (
+	asStaff caller = iota
+	asNobody
+	// asCat is busyCat, the spy on the active mission
+	asCat
+	// asOtherCat is idleCat, it has no mission
+	asOtherCat
+)
+
+func (f *fixture) authorize(t *testing.T, req *http.Request, w *world, as caller) {
+	t.Helper()
+
+	var catID int64
+	switch as {
+	case asStaff:
+		req.Header.Set("X-API-Key", testAPIKey)
+		return
+	case asNobody:
+		return
+	case asCat:
+		catID = w.busyCat.ID
+	case asOtherCat:
+		catID = w.idleCat.ID
+	}
+
+	token, err := f.auth.Issue(auth.Principal{Subject: fmt.Sprintf("cat:%d", catID), Role: auth.RoleCat, CatID: catID}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+type apiCase struct {
+	name   string
+	method string
+	path   func(w *world) string
+	body   string
+	as     caller
+	// ifMatch is sent as If-Match when set
+	ifMatch string
+	// before changes the world ahead of the request
+	before func(t *testing.T, s store.Storage, w *world)
+
+	wantStatus int
+	// wantCode is checked on problem responses
+	wantCode problem.Code
+	// check inspects the store after a successful request
+	check func(t *testing.T, s store.Storage, w *world, body []byte)
+}
+
+const (
+	anyTag = "*"
+	// seededTag is the ETag of a seeded resource nothing has changed since
+	// it was created
+	seededTag = `"1"`
+)
+
+func path(format string, ids ...func(w *world) int64) func(w *world) string {
+	return func(w *world) string {
+		args := make([]any, len(ids))
+		for i, id := range ids {
+			args[i] = id(w)
+		}
+		return fmt.Sprintf(format, args...)
+	}
+}
+
+// monthsAgo formats a month relative to the one salaries recorded now take
+// effect in.
+func monthsAgo(months int) string {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month()-time.Month(months), 1, 0, 0, 0, 0, time.UTC).Format(store.PayrollMonthLayout)
+}
+
+func fixed(id int64) func(*world) int64 { return func(*world) int64 { return id } }
+
+var (
+	idleCat         = func(w *world) int64 { return w.idleCat.ID }
+	busyCat         = func(w *world) int64 { return w.busyCat.ID }
+	assignedCat     = func(w *world) int64 { return w.assignedCat.ID }
+	draftMission    = func(w *world) int64 { return w.draft.ID }
+	activeMission   = func(w *world) int64 { return w.active.ID }
+	assignedMission = func(w *world) int64 { return w.assigned.ID }
+	finishedMission = func(w *world) int64 { return w.finished.ID }
+	fullMission     = func(w *world) int64 { return w.full.ID }
+	draftTarget     = func(w *world) int64 { return w.draft.Targets[0].ID }
+	openTarget      = func(w *world) int64 { return w.active.Targets[1].ID }
+	completeTarget  = func(w *world) int64 { return w.active.Targets[0].ID }
+	assignedTarget  = func(w *world) int64 { return w.assigned.Targets[0].ID }
+	singleTarget    = func(w *world) int64 { return w.single.Targets[0].ID }
+	finishedTarget  = func(w *world) int64 { return w.finished.Targets[0].ID }
+	draftNote       = func(w *world) int64 { return w.draftNote.ID }
+	activeNote      = func(w *world) int64 { return w.activeNote.ID }
+	frozenNote      = func(w *world) int64 { return w.frozenNote.ID }
+	missing         = fixed(missingID)
+	notANumber      = func(*world) string { return "/v1/cats/abc" }
+	missingTarget   = func(w *world) string { return fmt.Sprintf("/v1/missions/targets/%d", missingID) }
+)
+
+var apiCases = []apiCase{
+	// docs
+	{name: "openapi is public", method: http.MethodGet, path: path("/v1/openapi.json"), as: asNobody, wantStatus: http.StatusOK},
+	{name: "docs page is public", method: http.MethodGet, path: path("/v1/docs"), as: asNobody, wantStatus: http.StatusOK},
+	{name: "metrics are public", method: http.MethodGet, path: path("/metrics"), as: asNobody, wantStatus: http.StatusOK},
+	{name: "liveness is public", method: http.MethodGet, path: path("/healthz"), as: asNobody, wantStatus: http.StatusOK},
+	{name: "readiness is public", method: http.MethodGet, path: path("/readyz"), as: asNobody, wantStatus: http.StatusOK},
+	{
+		name: "version is public", method: http.MethodGet, path: path("/version"), as: asNobody, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var version struct {
+				Commit        string `json:"commit"`
+				SchemaVersion *uint  `json:"schema_version"`
+			}
+			decode(t, body, &version)
+			if version.Commit == "" || version.SchemaVersion != nil {
+				t.Fatalf("got %+v, want a commit and no schema on the memory store", version)
+			}
+		},
+	},
+
+	// auth
+	{name: "no credentials", method: http.MethodGet, path: path("/v1/cats/"), as: asNobody, wantStatus: http.StatusUnauthorized, wantCode: problem.Unauthorized},
+	{name: "cat cannot list cats", method: http.MethodGet, path: path("/v1/cats/"), as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+	{name: "cat cannot create missions", method: http.MethodPost, path: path("/v1/missions/"), body: `{"targets":[{"name":"a","country":"b"}]}`, as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+	{name: "cat cannot read notes", method: http.MethodGet, path: path("/v1/missions/targets/note/%d", openTarget), as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+
+	// cats
+	{
+		name: "list cats", method: http.MethodGet, path: path("/v1/cats/?limit=2&sort=name"), wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var page store.Page[store.Cat]
+			decode(t, body, &page)
+			if len(page.Data) != 2 || page.Data[0].Name != "Assigned" || page.NextCursor == "" {
+				t.Fatalf("got page %+v", page)
+			}
+		},
+	},
+	{name: "list cats with limit over max", method: http.MethodGet, path: path("/v1/cats/?limit=500"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "list cats by unknown field", method: http.MethodGet, path: path("/v1/cats/?sort=breed"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "list cats with bad order", method: http.MethodGet, path: path("/v1/cats/?order=up"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "list cats with bad cursor", method: http.MethodGet, path: path("/v1/cats/?cursor=nope"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidCursor},
+	{name: "get cat", method: http.MethodGet, path: path("/v1/cats/%d", idleCat), wantStatus: http.StatusOK},
+	{name: "get missing cat", method: http.MethodGet, path: path("/v1/cats/%d", missing), wantStatus: http.StatusNotFound, wantCode: problem.CatNotFound},
+	{name: "get cat by bad id", method: http.MethodGet, path: notANumber, wantStatus: http.StatusBadRequest, wantCode: problem.InvalidID},
+	{
+		name: "hire cat", method: http.MethodPost, path: path("/v1/cats/"),
+		body:       `{"name":"Tom","years_of_experience":3,"breed":"siamese","salary":1200}`,
+		wantStatus: http.StatusCreated,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var cat store.Cat
+			decode(t, body, &cat)
+			if _, err := s.Cat.GetByID(context.Background(), cat.ID); err != nil {
+				t.Fatalf("hired cat is not stored: %v", err)
+			}
+		},
+	},
+	{name: "hire cat of unknown breed", method: http.MethodPost, path: path("/v1/cats/"), body: `{"name":"Tom","years_of_experience":3,"breed":"Dragon","salary":1}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "hire nameless cat", method: http.MethodPost, path: path("/v1/cats/"), body: `{"name":"  ","years_of_experience":3,"breed":"Siamese","salary":1}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "hire cat from broken json", method: http.MethodPost, path: path("/v1/cats/"), body: `{"name":`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.InvalidBody},
+	{
+		name: "change salary", method: http.MethodPut, path: path("/v1/cats/%d", idleCat), ifMatch: seededTag, body: `{"salary":1500}`, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			cat, err := s.Cat.GetByID(context.Background(), w.idleCat.ID)
+			must(t, err)
+			if cat.Salary != 1500 {
+				t.Fatalf("got salary %v, want 1500", cat.Salary)
+			}
+
+			history, err := s.Cat.SalaryHistory(context.Background(), w.idleCat.ID)
+			must(t, err)
+			if len(history) != 2 || history[1].Salary != 1500 || history[1].ChangedBy != "service:suite" {
+				t.Fatalf("got salary history %+v, want the change recorded by service:suite", history)
+			}
+		},
+	},
+	{name: "change salary to negative", method: http.MethodPut, path: path("/v1/cats/%d", idleCat), ifMatch: anyTag, body: `{"salary":-1}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "change salary without salary", method: http.MethodPut, path: path("/v1/cats/%d", idleCat), ifMatch: anyTag, body: `{}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "change salary of missing cat", method: http.MethodPut, path: path("/v1/cats/%d", missing), ifMatch: anyTag, body: `{"salary":1}`, wantStatus: http.StatusNotFound, wantCode: problem.CatNotFound},
+	{
+		name: "salary history", method: http.MethodGet, path: path("/v1/cats/%d/salary-history", idleCat), wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var history []store.SalaryChange
+			must(t, json.Unmarshal(body, &history))
+			if len(history) != 1 || history[0].Salary != 1000 || history[0].ChangedBy != store.SystemActor {
+				t.Fatalf("got salary history %+v, want the hiring salary", history)
+			}
+		},
+	},
+	{name: "salary history of missing cat", method: http.MethodGet, path: path("/v1/cats/%d/salary-history", missing), wantStatus: http.StatusNotFound, wantCode: problem.CatNotFound},
+	{
+		name: "payroll", method: http.MethodGet, path: func(*world) string { return "/v1/payroll?from=" + monthsAgo(12) + "&to=" + monthsAgo(0) }, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var payroll struct {
+				Total float64             `json:"total"`
+				Lines []store.PayrollLine `json:"lines"`
+			}
+			must(t, json.Unmarshal(body, &payroll))
+
+			want := store.PayrollLine{Month: monthsAgo(0), Breed: "Siamese", Experience: "0-2", Cats: 4, Total: 4000}
+			if len(payroll.Lines) != 1 || payroll.Lines[0] != want || payroll.Total != 4000 {
+				t.Fatalf("got payroll %+v, want only %+v, cats were hired this month", payroll, want)
+			}
+		},
+	},
+	{name: "payroll with bad month", method: http.MethodGet, path: path("/v1/payroll?from=2024-13&to=2024-12"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "payroll ending before it starts", method: http.MethodGet, path: path("/v1/payroll?from=2024-05&to=2024-04"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "payroll over too many months", method: http.MethodGet, path: path("/v1/payroll?from=2000-01&to=2024-01"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{
+		name: "audit log", method: http.MethodGet, path: path("/v1/audit?entity=cat&action=create&limit=3"), wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var page store.Page[store.AuditEntry]
+			must(t, json.Unmarshal(body, &page))
+			if len(page.Data) != 3 || page.NextCursor == "" || page.Data[0].EntityID != w.finishedCat.ID || page.Data[0].Actor != store.SystemActor {
+				t.Fatalf("got %+v, want the three latest hires by %s and a cursor", page, store.SystemActor)
+			}
+		},
+	},
+	{name: "audit log of unknown action", method: http.MethodGet, path: path("/v1/audit?action=rename"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{
+		// an hour ahead in UTC-5 reads as four hours ago when the offset is dropped
+		name: "audit log from later with offset", method: http.MethodGet, wantStatus: http.StatusOK,
+		path: func(*world) string {
+			later := time.Now().Add(time.Hour).In(time.FixedZone("", -5*60*60))
+			return "/v1/audit?from=" + url.QueryEscape(later.Format(time.RFC3339))
+		},
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var page store.Page[store.AuditEntry]
+			must(t, json.Unmarshal(body, &page))
+			if len(page.Data) != 0 {
+				t.Fatalf("got %d entries, want none from an hour ahead", len(page.Data))
+			}
+		},
+	},
+	{name: "audit log with bad time", method: http.MethodGet, path: path("/v1/audit?from=2024-01-01"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "audit log ending before it starts", method: http.MethodGet, path: path("/v1/audit?from=2024-05-01T00:00:00Z&to=2024-04-01T00:00:00Z"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "audit log with bad cursor", method: http.MethodGet, path: path("/v1/audit?cursor=garbage"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidCursor},
+	{name: "audit log as cat", method: http.MethodGet, path: path("/v1/audit"), as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+	{name: "fire cat", method: http.MethodDelete, path: path("/v1/cats/%d", idleCat), ifMatch: seededTag, wantStatus: http.StatusOK},
+	{name: "fire missing cat", method: http.MethodDelete, path: path("/v1/cats/%d", missing), ifMatch: anyTag, wantStatus: http.StatusNotFound, wantCode: problem.CatNotFound},
+	{name: "fire cat on mission", method: http.MethodDelete, path: path("/v1/cats/%d", busyCat), ifMatch: anyTag, wantStatus: http.StatusConflict, wantCode: problem.CatOnMission},
+	{
+		name: "fire cat handing mission over", method: http.MethodDelete, path: path("/v1/cats/%d?reassign_to=%d", busyCat, idleCat), ifMatch: anyTag, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			mission, err := s.Mission.GetByID(context.Background(), w.active.ID)
+			must(t, err)
+			if mission.CatID == nil || *mission.CatID != w.idleCat.ID || mission.Status != store.MissionInProgress {
+				t.Fatalf("got %+v, want the mission still in progress with the idle cat", mission)
+			}
+		},
+	},
+	{name: "fire cat handing mission to busy cat", method: http.MethodDelete, path: path("/v1/cats/%d?reassign_to=%d", busyCat, assignedCat), ifMatch: anyTag, wantStatus: http.StatusBadRequest, wantCode: problem.CatBusy},
+	{name: "fire cat handing mission to missing cat", method: http.MethodDelete, path: path("/v1/cats/%d?reassign_to=%d", busyCat, missing), ifMatch: anyTag, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.InvalidSuccessor},
+	{name: "fire cat handing mission to itself", method: http.MethodDelete, path: path("/v1/cats/%d?reassign_to=%d", busyCat, busyCat), ifMatch: anyTag, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.InvalidSuccessor},
+	{name: "fire cat with bad reassign_to", method: http.MethodDelete, path: path("/v1/cats/%d?reassign_to=-1", busyCat), ifMatch: anyTag, wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "fire cat without If-Match", method: http.MethodDelete, path: path("/v1/cats/%d", idleCat), wantStatus: http.StatusPreconditionRequired, wantCode: problem.PreconditionRequired},
+	{name: "fire cat with weak ETag", method: http.MethodDelete, path: path("/v1/cats/%d", idleCat), ifMatch: `W/"1"`, wantStatus: http.StatusPreconditionFailed, wantCode: problem.PreconditionFailed},
+	{
+		name: "fire cat after salary change", method: http.MethodDelete, path: path("/v1/cats/%d", idleCat), ifMatch: seededTag,
+		before: func(t *testing.T, s store.Storage, w *world) {
+			must(t, s.Cat.Update(context.Background(), &store.Cat{ID: w.idleCat.ID, Salary: 1100}))
+		},
+		wantStatus: http.StatusPreconditionFailed, wantCode: problem.PreconditionFailed,
+	},
+	{name: "change salary with stale ETag", method: http.MethodPut, path: path("/v1/cats/%d", idleCat), ifMatch: `"2"`, body: `{"salary":1500}`, wantStatus: http.StatusPreconditionFailed, wantCode: problem.PreconditionFailed},
+	{
+		name: "list cats with deleted", method: http.MethodGet, path: path("/v1/cats/?include_deleted=true&sort=name"), wantStatus: http.StatusOK,
+		before: func(t *testing.T, s store.Storage, w *world) {
+			must(t, s.Cat.Delete(context.Background(), w.idleCat.ID, store.AnyVersion))
+		},
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var page store.Page[store.Cat]
+			must(t, json.Unmarshal(body, &page))
+			for _, cat := range page.Data {
+				if cat.ID == w.idleCat.ID && cat.DeletedAt != nil {
+					return
+				}
+			}
+			t.Fatalf("got %+v, want the fired cat with deleted_at", page.Data)
+		},
+	},
+	{
+		name: "restore cat", method: http.MethodPost, path: path("/v1/cats/%d/restore", idleCat), wantStatus: http.StatusOK,
+		before: func(t *testing.T, s store.Storage, w *world) {
+			must(t, s.Cat.Delete(context.Background(), w.idleCat.ID, store.AnyVersion))
+		},
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			if _, err := s.Cat.GetByID(context.Background(), w.idleCat.ID); err != nil {
+				t.Fatalf("restored cat is still gone: %v", err)
+			}
+		},
+	},
+	{name: "restore cat that is not deleted", method: http.MethodPost, path: path("/v1/cats/%d/restore", idleCat), wantStatus: http.StatusConflict, wantCode: problem.NotDeleted},
+	{name: "restore missing cat", method: http.MethodPost, path: path("/v1/cats/%d/restore", missing), wantStatus: http.StatusNotFound, wantCode: problem.CatNotFound},
+	{name: "restore cat as cat", method: http.MethodPost, path: path("/v1/cats/%d/restore", idleCat), as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+
+	// missions
+	{name: "list missions", method: http.MethodGet, path: path("/v1/missions/?limit=2"), wantStatus: http.StatusOK},
+	{name: "list missions with limit over max", method: http.MethodGet, path: path("/v1/missions/?limit=101"), wantStatus: http.StatusBadRequest, wantCode: problem.InvalidQuery},
+	{name: "get mission", method: http.MethodGet, path: path("/v1/missions/%d", draftMission), wantStatus: http.StatusOK},
+	{
+		name: "get mission with notes", method: http.MethodGet, path: path("/v1/missions/%d?include=notes", draftMission), wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var mission store.Mission
+			decode(t, body, &mission)
+			if len(mission.Targets) != 2 || len(mission.Targets[0].Notes) != 1 {
+				t.Fatalf("got %+v, want the note on the first target", mission.Targets)
+			}
+		},
+	},
+	{name: "get missing mission", method: http.MethodGet, path: path("/v1/missions/%d", missing), wantStatus: http.StatusNotFound, wantCode: problem.MissionNotFound},
+	{name: "create mission", method: http.MethodPost, path: path("/v1/missions/"), body: `{"targets":[{"name":"Jerry","country":"USA"}]}`, wantStatus: http.StatusCreated},
+	{name: "create mission without targets", method: http.MethodPost, path: path("/v1/missions/"), body: `{"targets":[]}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{
+		name: "create mission with too many targets", method: http.MethodPost, path: path("/v1/missions/"),
+		body:       `{"targets":[{"name":"a","country":"a"},{"name":"b","country":"b"},{"name":"c","country":"c"},{"name":"d","country":"d"}]}`,
+		wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed,
+	},
+	{
+		name: "delete mission", method: http.MethodDelete, path: path("/v1/missions/%d", draftMission), ifMatch: seededTag, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			_, err := s.Mission.GetNoteByID(context.Background(), w.draftNote.ID)
+			if err == nil {
+				t.Fatal("notes of the deleted mission survived")
+			}
+		},
+	},
+	{name: "delete mission with spy", method: http.MethodDelete, path: path("/v1/missions/%d", assignedMission), ifMatch: anyTag, wantStatus: http.StatusBadRequest, wantCode: problem.MissionHasSpy},
+	{name: "delete missing mission", method: http.MethodDelete, path: path("/v1/missions/%d", missing), ifMatch: anyTag, wantStatus: http.StatusNotFound, wantCode: problem.MissionNotFound},
+	{
+		name: "delete mission after target added", method: http.MethodDelete, path: path("/v1/missions/%d", draftMission), ifMatch: seededTag,
+		before: func(t *testing.T, s store.Storage, w *world) {
+			must(t, s.Mission.AddTarget(context.Background(), w.draft.ID, &store.Target{Name: "Spike", Country: "UK"}))
+		},
+		wantStatus: http.StatusPreconditionFailed, wantCode: problem.PreconditionFailed,
+	},
+	{
+		name: "restore mission", method: http.MethodPost, path: path("/v1/missions/%d/restore", draftMission), wantStatus: http.StatusOK,
+		before: func(t *testing.T, s store.Storage, w *world) {
+			must(t, s.Mission.Delete(context.Background(), w.draft.ID, store.AnyVersion))
+		},
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			if _, err := s.Mission.GetNoteByID(context.Background(), w.draftNote.ID); err != nil {
+				t.Fatalf("notes of the restored mission are still gone: %v", err)
+			}
+		},
+	},
+	{name: "restore mission that is not deleted", method: http.MethodPost, path: path("/v1/missions/%d/restore", draftMission), wantStatus: http.StatusConflict, wantCode: problem.NotDeleted},
+	{name: "restore missing mission", method: http.MethodPost, path: path("/v1/missions/%d/restore", missing), wantStatus: http.StatusNotFound, wantCode: problem.MissionNotFound},
+
+	// assignment and lifecycle
+	{name: "assign cat", method: http.MethodPut, path: path("/v1/missions/%d/%d/assign", draftMission, idleCat), ifMatch: seededTag, wantStatus: http.StatusOK},
+	{name: "assign busy cat", method: http.MethodPut, path: path("/v1/missions/%d/%d/assign", draftMission, busyCat), ifMatch: anyTag, wantStatus: http.StatusBadRequest, wantCode: problem.CatBusy},
+	{name: "assign cat to mission with spy", method: http.MethodPut, path: path("/v1/missions/%d/%d/assign", assignedMission, idleCat), ifMatch: anyTag, wantStatus: http.StatusBadRequest, wantCode: problem.MissionHasSpy},
+	{name: "assign missing cat", method: http.MethodPut, path: path("/v1/missions/%d/%d/assign", draftMission, missing), ifMatch: anyTag, wantStatus: http.StatusNotFound, wantCode: problem.CatNotFound},
+	{name: "assign cat to missing mission", method: http.MethodPut, path: path("/v1/missions/%d/%d/assign", missing, idleCat), ifMatch: anyTag, wantStatus: http.StatusNotFound, wantCode: problem.MissionNotFound},
+	{name: "start mission", method: http.MethodPost, path: path("/v1/missions/%d/start", assignedMission), wantStatus: http.StatusOK},
+	{name: "start draft mission", method: http.MethodPost, path: path("/v1/missions/%d/start", draftMission), wantStatus: http.StatusConflict, wantCode: problem.InvalidTransition},
+	{name: "complete mission with open targets", method: http.MethodPost, path: path("/v1/missions/%d/complete", activeMission), wantStatus: http.StatusBadRequest, wantCode: problem.TargetsIncomplete},
+	{
+		name: "complete mission", method: http.MethodPost, path: path("/v1/missions/%d/complete", activeMission),
+		before: func(t *testing.T, s store.Storage, w *world) {
+			target := w.active.Targets[1]
+			target.IsComplete = true
+			must(t, s.Mission.UpdateTarget(context.Background(), &target))
+		},
+		wantStatus: http.StatusOK,
+	},
+	{name: "complete missing mission", method: http.MethodPost, path: path("/v1/missions/%d/complete", missing), wantStatus: http.StatusNotFound, wantCode: problem.MissionNotFound},
+	{name: "abort mission", method: http.MethodPost, path: path("/v1/missions/%d/abort", activeMission), wantStatus: http.StatusOK},
+	{name: "abort finished mission", method: http.MethodPost, path: path("/v1/missions/%d/abort", finishedMission), wantStatus: http.StatusConflict, wantCode: problem.InvalidTransition},
+
+	// targets
+	{name: "add target", method: http.MethodPost, path: path("/v1/missions/targets/%d", draftMission), body: `{"name":"Spike","country":"UK"}`, wantStatus: http.StatusOK},
+	{name: "add fourth target", method: http.MethodPost, path: path("/v1/missions/targets/%d", fullMission), body: `{"name":"Spike","country":"UK"}`, wantStatus: http.StatusBadRequest, wantCode: problem.TargetLimitReached},
+	{name: "add target without country", method: http.MethodPost, path: path("/v1/missions/targets/%d", draftMission), body: `{"name":"Spike"}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "add target to missing mission", method: http.MethodPost, path: path("/v1/missions/targets/%d", missing), body: `{"name":"Spike","country":"UK"}`, wantStatus: http.StatusNotFound, wantCode: problem.MissionNotFound},
+	{name: "add target to finished mission", method: http.MethodPost, path: path("/v1/missions/targets/%d", finishedMission), body: `{"name":"Spike","country":"UK"}`, wantStatus: http.StatusBadRequest, wantCode: problem.MissionFinished},
+	{name: "delete target", method: http.MethodDelete, path: path("/v1/missions/targets/%d", draftTarget), ifMatch: seededTag, wantStatus: http.StatusOK},
+	{name: "delete last target", method: http.MethodDelete, path: path("/v1/missions/targets/%d", singleTarget), ifMatch: anyTag, wantStatus: http.StatusBadRequest, wantCode: problem.LastTarget},
+	{name: "delete completed target", method: http.MethodDelete, path: path("/v1/missions/targets/%d", completeTarget), ifMatch: anyTag, wantStatus: http.StatusBadRequest, wantCode: problem.TargetComplete},
+	{name: "delete missing target", method: http.MethodDelete, path: missingTarget, ifMatch: anyTag, wantStatus: http.StatusNotFound, wantCode: problem.TargetNotFound},
+	{name: "get target", method: http.MethodGet, path: path("/v1/missions/targets/%d", openTarget), wantStatus: http.StatusOK},
+	{
+		name: "cat gets own target", method: http.MethodGet, path: path("/v1/missions/targets/%d", openTarget), as: asCat, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			var target store.Target
+			must(t, json.Unmarshal(body, &target))
+			if target.ID != w.active.Targets[1].ID || target.Version != 1 {
+				t.Fatalf("got target %d version %d", target.ID, target.Version)
+			}
+		},
+	},
+	{name: "cat gets someone else's target", method: http.MethodGet, path: path("/v1/missions/targets/%d", assignedTarget), as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+	{name: "get missing target", method: http.MethodGet, path: missingTarget, wantStatus: http.StatusNotFound, wantCode: problem.TargetNotFound},
+	{
+		name: "complete target", method: http.MethodPut, path: path("/v1/missions/targets/%d", openTarget), ifMatch: seededTag, body: `{"is_complete":true}`, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			target, err := s.Mission.GetTargetByID(context.Background(), w.active.Targets[1].ID)
+			must(t, err)
+			if !target.IsComplete {
+				t.Fatal("target was not completed")
+			}
+		},
+	},
+	{name: "complete target with stale ETag", method: http.MethodPut, path: path("/v1/missions/targets/%d", openTarget), ifMatch: `"2"`, body: `{"is_complete":true}`, wantStatus: http.StatusPreconditionFailed, wantCode: problem.PreconditionFailed},
+	{name: "assign cat with stale ETag", method: http.MethodPut, path: path("/v1/missions/%d/%d/assign", draftMission, idleCat), ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed, wantCode: problem.PreconditionFailed},
+	{name: "cat completes own target", method: http.MethodPut, path: path("/v1/missions/targets/%d", openTarget), ifMatch: seededTag, body: `{"is_complete":true}`, as: asCat, wantStatus: http.StatusOK},
+	{name: "cat completes someone else's target", method: http.MethodPut, path: path("/v1/missions/targets/%d", assignedTarget), body: `{"is_complete":true}`, as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+	{name: "cat without mission completes target", method: http.MethodPut, path: path("/v1/missions/targets/%d", openTarget), body: `{"is_complete":true}`, as: asOtherCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+	{name: "anonymous completes target", method: http.MethodPut, path: path("/v1/missions/targets/%d", openTarget), body: `{"is_complete":true}`, as: asNobody, wantStatus: http.StatusUnauthorized, wantCode: problem.Unauthorized},
+	{name: "update completed target", method: http.MethodPut, path: path("/v1/missions/targets/%d", completeTarget), ifMatch: anyTag, body: `{"is_complete":false}`, wantStatus: http.StatusBadRequest, wantCode: problem.TargetComplete},
+	{name: "update target of mission without spy", method: http.MethodPut, path: path("/v1/missions/targets/%d", draftTarget), ifMatch: anyTag, body: `{"is_complete":true}`, wantStatus: http.StatusBadRequest, wantCode: problem.MissionHasNoSpy},
+	{
+		name: "update target of aborted mission", method: http.MethodPut, path: path("/v1/missions/targets/%d", assignedTarget), ifMatch: anyTag, body: `{"is_complete":true}`,
+		before: func(t *testing.T, s store.Storage, w *world) {
+			must(t, s.Mission.Update(context.Background(), &store.Mission{ID: w.assigned.ID, Status: store.MissionAborted}))
+		},
+		wantStatus: http.StatusBadRequest, wantCode: problem.MissionFinished,
+	},
+	{name: "update target without flag", method: http.MethodPut, path: path("/v1/missions/targets/%d", openTarget), ifMatch: anyTag, body: `{}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "update missing target", method: http.MethodPut, path: missingTarget, ifMatch: anyTag, body: `{"is_complete":true}`, wantStatus: http.StatusNotFound, wantCode: problem.TargetNotFound},
+
+	// notes
+	{name: "list notes", method: http.MethodGet, path: path("/v1/missions/targets/note/%d", openTarget), wantStatus: http.StatusOK},
+	{name: "list notes of missing target", method: http.MethodGet, path: path("/v1/missions/targets/note/%d", missing), wantStatus: http.StatusNotFound, wantCode: problem.TargetNotFound},
+	{name: "add note", method: http.MethodPost, path: path("/v1/missions/targets/note/%d", draftTarget), body: `{"note":"seen at the market"}`, wantStatus: http.StatusOK},
+	{name: "cat adds note on own target", method: http.MethodPost, path: path("/v1/missions/targets/note/%d", openTarget), body: `{"note":"on it"}`, as: asCat, wantStatus: http.StatusOK},
+	{name: "cat adds note on someone else's target", method: http.MethodPost, path: path("/v1/missions/targets/note/%d", draftTarget), body: `{"note":"hi"}`, as: asCat, wantStatus: http.StatusForbidden, wantCode: problem.Forbidden},
+	{name: "add note on completed target", method: http.MethodPost, path: path("/v1/missions/targets/note/%d", completeTarget), body: `{"note":"late"}`, wantStatus: http.StatusBadRequest, wantCode: problem.NotesFrozen},
+	{name: "add note on finished mission", method: http.MethodPost, path: path("/v1/missions/targets/note/%d", finishedTarget), body: `{"note":"late"}`, wantStatus: http.StatusBadRequest, wantCode: problem.NotesFrozen},
+	{name: "add blank note", method: http.MethodPost, path: path("/v1/missions/targets/note/%d", draftTarget), body: `{"note":""}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "get note", method: http.MethodGet, path: path("/v1/missions/targets/note/%d/%d", draftTarget, draftNote), wantStatus: http.StatusOK},
+	{name: "get note through another target", method: http.MethodGet, path: path("/v1/missions/targets/note/%d/%d", openTarget, draftNote), wantStatus: http.StatusNotFound, wantCode: problem.NoteNotFound},
+	{name: "get missing note", method: http.MethodGet, path: path("/v1/missions/targets/note/%d/%d", draftTarget, missing), wantStatus: http.StatusNotFound, wantCode: problem.NoteNotFound},
+	{
+		name: "edit note", method: http.MethodPut, path: path("/v1/missions/targets/note/%d/%d", openTarget, activeNote), body: `{"note":"edited"}`, wantStatus: http.StatusOK,
+		check: func(t *testing.T, s store.Storage, w *world, body []byte) {
+			note, err := s.Mission.GetNoteByID(context.Background(), w.activeNote.ID)
+			must(t, err)
+			if note.Note != "edited" {
+				t.Fatalf("got note %q, want edited", note.Note)
+			}
+		},
+	},
+	{name: "edit frozen note", method: http.MethodPut, path: path("/v1/missions/targets/note/%d/%d", completeTarget, frozenNote), body: `{"note":"edited"}`, wantStatus: http.StatusBadRequest, wantCode: problem.NotesFrozen},
+	{name: "edit note with blank text", method: http.MethodPut, path: path("/v1/missions/targets/note/%d/%d", openTarget, activeNote), body: `{"note":" "}`, wantStatus: http.StatusUnprocessableEntity, wantCode: problem.ValidationFailed},
+	{name: "delete note", method: http.MethodDelete, path: path("/v1/missions/targets/note/%d/%d", openTarget, activeNote), wantStatus: http.StatusOK},
+	{name: "delete frozen note", method: http.MethodDelete, path: path("/v1/missions/targets/note/%d/%d", completeTarget, frozenNote), wantStatus: http.StatusBadRequest, wantCode: problem.NotesFrozen},
+	{name: "delete missing note", method: http.MethodDelete, path: path("/v1/missions/targets/note/%d/%d", openTarget, missing), wantStatus: http.StatusNotFound, wantCode: problem.NoteNotFound},
+}
+
+func TestHandlers(t *testing.T) {
+	hits := &routeHits{seen: map[string]bool{}}
+
+	for _, tc := range apiCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture(t, hits)
+			w := seedWorld(t, f.store)
+
+			if tc.before != nil {
+				tc.before(t, f.store, w)
+			}
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path(w), body)
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			f.authorize(t, req, w, tc.as)
+
+			rec := httptest.NewRecorder()
+			f.router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+
+			if tc.wantCode != "" {
+				if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, problem.ContentType) {
+					t.Fatalf("got content type %q, want %s", ct, problem.ContentType)
+				}
+
+				var p problem.Problem
+				decode(t, rec.Body.Bytes(), &p)
+				if p.Code != tc.wantCode || p.Status != tc.wantStatus {
+					t.Fatalf("got problem %s/%d, want %s/%d", p.Code, p.Status, tc.wantCode, tc.wantStatus)
+				}
+			}
+
+			if tc.check != nil {
+				tc.check(t, f.store, w, rec.Body.Bytes())
+			}
+		})
+	}
+
+	// every mounted route needs at least one case
+	f := newFixture(t, &routeHits{seen: map[string]bool{}})
+	for _, r := range f.router.Routes() {
+		if !hits.seen[r.Method+" "+r.Path] {
+			t.Errorf("route %s %s has no test case", r.Method, r.Path)
+		}
+	}
+}
+
+// TestServersAreIndependent runs two servers side by side, each sees only
+// its own store.
+func TestServersAreIndependent(t *testing.T) {
+	hits := &routeHits{seen: map[string]bool{}}
+	first, second := newFixture(t, hits), newFixture(t, hits)
+
+	cat := store.Cat{Name: "Only here", Breed: "Siamese"}
+	must(t, first.store.Cat.Create(context.Background(), &cat))
+
+	for _, tc := range []struct {
+		f    *fixture
+		want int
+	}{{first, http.StatusOK}, {second, http.StatusNotFound}} {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/cats/%d", cat.ID), nil)
+		req.Header.Set("X-API-Key", testAPIKey)
+
+		rec := httptest.NewRecorder()
+		tc.f.router.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("got status %d, want %d", rec.Code, tc.want)
+		}
+	}
+}
+
+// TestTokenWithoutSubject makes sure a token nobody can be held to account
+// for is neither issued nor accepted.
+func TestTokenWithoutSubject(t *testing.T) {
+	f := newFixture(t, &routeHits{seen: map[string]bool{}})
+
+	if _, err := f.auth.Issue(auth.Principal{Role: auth.RoleStaff}, time.Hour); err == nil {
+		t.Fatal("issued a token without a subject")
+	}
+
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":  "spy-cat-agency",
+		"role": auth.RoleStaff,
+		"iat":  now.Unix(),
+		"exp":  now.Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSigningKey))
+	must(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/cats/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d, want 401 for a token without a subject", rec.Code)
+	}
+}
+
+// TestETagRoundTrip updates a cat with the ETag it was read with, the old
+// tag stops matching once the write went through.
+func TestETagRoundTrip(t *testing.T) {
+	f := newFixture(t, &routeHits{seen: map[string]bool{}})
+	w := seedWorld(t, f.store)
+
+	send := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(method, fmt.Sprintf("/v1/cats/%d", w.idleCat.ID), strings.NewReader(body))
+		req.Header.Set("X-API-Key", testAPIKey)
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+
+		rec := httptest.NewRecorder()
+		f.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	read := send(http.MethodGet, "", "")
+	etag := read.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("got ETag %q on read, want \"1\"", etag)
+	}
+
+	updated := send(http.MethodPut, etag, `{"salary":1500}`)
+	if updated.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", updated.Code, updated.Body)
+	}
+	if got := updated.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("got ETag %q after update, want \"2\"", got)
+	}
+
+	if stale := send(http.MethodPut, etag, `{"salary":1600}`); stale.Code != http.StatusPreconditionFailed {
+		t.Fatalf("got status %d for the stale ETag, want 412", stale.Code)
+	}
+}
+
+// TestAuditTrail fires a cat and finds who did it, and in which request, in
+// the audit log.
+func TestAuditTrail(t *testing.T) {
+	f := newFixture(t, &routeHits{seen: map[string]bool{}})
+	w := seedWorld(t, f.store)
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/v1/cats/%d", w.idleCat.ID), nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("X-Request-ID", "fire-idle-cat")
+	req.Header.Set("If-Match", anyTag)
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/audit?entity=cat&entity_id=%d", w.idleCat.ID), nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	rec = httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+
+	var page store.Page[store.AuditEntry]
+	decode(t, rec.Body.Bytes(), &page)
+	if len(page.Data) != 2 {
+		t.Fatalf("got %+v, want the hire and the firing", page.Data)
+	}
+
+	fired := page.Data[0]
+	if fired.Action != store.AuditDelete || fired.Actor != "service:suite" || fired.RequestID != "fire-idle-cat" || string(fired.After) != "null" {
+		t.Fatalf("got %+v, want the firing by service:suite in request fire-idle-cat", fired)
+	}
+
+	var before store.Cat
+	decode(t, fired.Before, &before)
+	if before.ID != w.idleCat.ID || before.Name != w.idleCat.Name {
+		t.Fatalf("got %+v before the firing, want %+v", before, *w.idleCat)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func decode(t *testing.T, body []byte, v any) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("failed to decode %s: %v", body, err)
+	}
+}
@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"spy-cat-agency/internal/api"
+	"spy-cat-agency/internal/api/handlers"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/auth"
+	"spy-cat-agency/internal/store"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// post sends body with an Idempotency-Key as staff.
+func post(router http.Handler, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func wantProblem(t *testing.T, rec *httptest.ResponseRecorder, status int, code problem.Code) {
+	t.Helper()
+
+	var p problem.Problem
+	decode(t, rec.Body.Bytes(), &p)
+	if rec.Code != status || p.Code != code {
+		t.Fatalf("got %d %s, want %d %s: %s", rec.Code, p.Code, status, code, rec.Body)
+	}
+}
+
+func TestIdempotentCreate(t *testing.T) {
+	f := newFixture(t, &routeHits{seen: map[string]bool{}})
+	w := seedWorld(t, f.store)
+	ctx := context.Background()
+
+	before, err := f.store.Mission.GetAll(ctx)
+	must(t, err)
+
+	body := `{"targets":[{"name":"Jerry","country":"USA"}]}`
+	first := post(f.router, "/v1/missions/", "mission-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want 201: %s", first.Code, first.Body)
+	}
+
+	retry := post(f.router, "/v1/missions/", "mission-1", body)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Fatalf("got %d %s, want the first response %s", retry.Code, retry.Body, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Fatalf("got headers %v, want the replayed ETag %s", retry.Header(), first.Header().Get("ETag"))
+	}
+
+	after, err := f.store.Mission.GetAll(ctx)
+	must(t, err)
+	if len(after) != len(before)+1 {
+		t.Fatalf("got %d missions, want one more than %d", len(after), len(before))
+	}
+
+	wantProblem(t, post(f.router, "/v1/missions/", "mission-1", `{"targets":[{"name":"Tom","country":"USA"}]}`),
+		http.StatusUnprocessableEntity, problem.IdempotencyKeyReused)
+	wantProblem(t, post(f.router, fmt.Sprintf("/v1/missions/targets/note/%d", w.draft.Targets[0].ID), "mission-1", `{"note":"same key"}`),
+		http.StatusUnprocessableEntity, problem.IdempotencyKeyReused)
+	wantProblem(t, post(f.router, "/v1/missions/", "bad key", body), http.StatusBadRequest, problem.InvalidIdempotencyKey)
+
+	if second := post(f.router, "/v1/missions/", "mission-2", body); second.Code != http.StatusCreated || second.Body.String() == first.Body.String() {
+		t.Fatalf("got %d %s, want a new mission for a new key", second.Code, second.Body)
+	}
+	if plain := post(f.router, "/v1/missions/", "", body); plain.Code != http.StatusCreated || plain.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("got %d %v, want a request without a key served as usual", plain.Code, plain.Header())
+	}
+
+	notePath := fmt.Sprintf("/v1/missions/targets/note/%d", w.draft.Targets[0].ID)
+	for range 2 {
+		if rec := post(f.router, notePath, "note-1", `{"note":"seen twice"}`); rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want 200: %s", rec.Code, rec.Body)
+		}
+	}
+	notes, err := f.store.Mission.GetAllTargetNotes(ctx, w.draft.Targets[0].ID)
+	must(t, err)
+	if len(notes) != 2 {
+		t.Fatalf("got %d notes, want the seeded one and one added", len(notes))
+	}
+}
+
+// gatedBreed holds breed checks until it is told how to answer.
+type gatedBreed struct {
+	entered chan struct{}
+	answer  chan error
+}
+
+func (g *gatedBreed) Validate(ctx context.Context, breed string) (bool, error) {
+	g.entered <- struct{}{}
+	return true, <-g.answer
+}
+
+func TestIdempotentCreateInFlight(t *testing.T) {
+	storage := store.NewMemoryStorage()
+	authenticator, err := auth.New([]byte("handler-suite-signing-key-0123456789"), map[string]string{"suite": testAPIKey})
+	must(t, err)
+
+	gate := &gatedBreed{entered: make(chan struct{}), answer: make(chan error)}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.Mount(router, handlers.New(storage, gate, authenticator, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil))
+
+	body := `{"name":"Tom","years_of_experience":3,"breed":"Siamese","salary":1200}`
+	serve := func() <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- post(router, "/v1/cats/", "cat-1", body) }()
+		<-gate.entered
+		return done
+	}
+
+	// a failed request gives the key up
+	failed := serve()
+	gate.answer <- errors.New("breed service is down")
+	wantProblem(t, <-failed, http.StatusInternalServerError, problem.BreedCheckFailed)
+
+	served := serve()
+	wantProblem(t, post(router, "/v1/cats/", "cat-1", body), http.StatusConflict, problem.IdempotencyKeyInUse)
+	gate.answer <- nil
+	first := <-served
+	if first.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want 201: %s", first.Code, first.Body)
+	}
+
+	// replays don't check the breed again, the gate would block them
+	if retry := post(router, "/v1/cats/", "cat-1", body); retry.Body.String() != first.Body.String() {
+		t.Fatalf("got %s, want the first response %s", retry.Body, first.Body)
+	}
+
+	cats, err := storage.Cat.GetAll(context.Background())
+	must(t, err)
+	if len(cats) != 1 {
+		t.Fatalf("got %d cats, want 1", len(cats))
+	}
+}
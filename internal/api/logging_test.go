@@ -0,0 +1,161 @@
+package api_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"spy-cat-agency/internal/api"
+	"spy-cat-agency/internal/api/handlers"
+	"spy-cat-agency/internal/auth"
+	"spy-cat-agency/internal/store"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// brokenBreeds fails every check, so CreateCat logs an error.
+type brokenBreeds struct{}
+
+func (brokenBreeds) Validate(context.Context, string) (bool, error) {
+	return false, errors.New("breed service down")
+}
+
+// loggedServer returns a router writing JSON logs into the returned buffer.
+func loggedServer(t *testing.T) (*gin.Engine, *bytes.Buffer, store.Storage) {
+	t.Helper()
+
+	authenticator, err := auth.New([]byte("logging-test-signing-key-0123456789"), map[string]string{"test": testAPIKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	storage := store.NewMemoryStorage()
+	now := func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.Mount(router, handlers.New(storage, brokenBreeds{}, authenticator, logger, nil, nil, now))
+
+	return router, &logs, storage
+}
+
+func logLines(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestLog(t *testing.T) {
+	router, logs, storage := loggedServer(t)
+
+	mission := &store.Mission{Targets: []store.Target{{Name: "Mr. X", Country: "Chile"}}}
+	must(t, storage.Mission.Create(context.Background(), mission))
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/missions/%d", mission.ID), nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("X-Request-ID", "trace-42")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Request-ID"); got != "trace-42" {
+		t.Fatalf("X-Request-ID = %q, want the id sent by the client", got)
+	}
+
+	lines := logLines(t, logs)
+	if len(lines) != 1 {
+		t.Fatalf("got %d log lines, want 1: %v", len(lines), lines)
+	}
+
+	want := map[string]any{
+		"msg":        "request",
+		"level":      "INFO",
+		"request_id": "trace-42",
+		"method":     "GET",
+		"route":      "/v1/missions/:missionID",
+		"status":     float64(http.StatusOK),
+		"missionID":  float64(mission.ID),
+		"client_ip":  "192.0.2.1",
+	}
+	for key, value := range want {
+		if lines[0][key] != value {
+			t.Errorf("%s = %v, want %v", key, lines[0][key], value)
+		}
+	}
+	if _, ok := lines[0]["latency"]; !ok {
+		t.Error("latency is missing")
+	}
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	router, logs, _ := loggedServer(t)
+
+	for _, sent := range []string{"", "has spaces", strings.Repeat("x", 129)} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/docs", nil)
+		if sent != "" {
+			req.Header.Set("X-Request-ID", sent)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		got := rec.Header().Get("X-Request-ID")
+		if got == "" || got == sent {
+			t.Errorf("sent %q, got X-Request-ID %q, want a new id", sent, got)
+		}
+	}
+
+	seen := map[any]bool{}
+	for _, line := range logLines(t, logs) {
+		seen[line["request_id"]] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("got %d distinct request ids, want 3", len(seen))
+	}
+}
+
+func TestErrorLogCarriesRequestID(t *testing.T) {
+	router, logs, _ := loggedServer(t)
+
+	body := `{"name": "Tom", "years_of_experience": 1, "breed": "Siamese", "salary": 10}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/cats/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("X-Request-ID", "trace-500")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+
+	lines := logLines(t, logs)
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want the error and the request: %v", len(lines), lines)
+	}
+	for _, line := range lines {
+		if line["level"] != "ERROR" || line["request_id"] != "trace-500" {
+			t.Errorf("line %v, want an ERROR tagged trace-500", line)
+		}
+	}
+	if lines[0]["error"] != "breed service down" {
+		t.Errorf("error = %v, want the breed failure", lines[0]["error"])
+	}
+}
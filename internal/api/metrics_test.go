@@ -0,0 +1,78 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"spy-cat-agency/internal/api"
+	"spy-cat-agency/internal/api/handlers"
+	"spy-cat-agency/internal/auth"
+	"spy-cat-agency/internal/metrics"
+	"spy-cat-agency/internal/store"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	authenticator, err := auth.New([]byte("metrics-test-signing-key-0123456789"), map[string]string{"test": testAPIKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := store.NewMemoryStorage()
+	idle := &store.Cat{Name: "Idle", YearsOfExperience: 1, Breed: "Siamese", Salary: 10}
+	busy := &store.Cat{Name: "Busy", YearsOfExperience: 1, Breed: "Siamese", Salary: 10}
+	must(t, storage.Cat.Create(ctx, idle))
+	must(t, storage.Cat.Create(ctx, busy))
+	mission := &store.Mission{Targets: []store.Target{{Name: "Mr. X", Country: "Chile"}}}
+	must(t, storage.Mission.Create(ctx, mission))
+	must(t, storage.Mission.AssignCat(ctx, busy.ID, mission.ID, store.AnyVersion))
+
+	m := metrics.New()
+	must(t, m.RegisterStore(storage))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	api.Mount(router, handlers.New(storage, m.InstrumentBreed(brokenBreeds{}), authenticator, logger, m, nil, nil))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/cats/%d", idle.ID), nil),
+		httptest.NewRequest(http.MethodGet, fmt.Sprintf("/v1/cats/%d", missingID), nil),
+		httptest.NewRequest(http.MethodPost, "/v1/cats/", strings.NewReader(`{"name": "Tom", "years_of_experience": 1, "breed": "Siamese", "salary": 10}`)),
+	} {
+		req.Header.Set("X-API-Key", testAPIKey)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`agency_http_request_duration_seconds_count{method="GET",route="/v1/cats/:catID",status="200"} 1`,
+		`agency_http_request_duration_seconds_count{method="GET",route="/v1/cats/:catID",status="404"} 1`,
+		`agency_http_request_duration_seconds_count{method="POST",route="/v1/cats/",status="500"} 1`,
+		`agency_http_problems_total{code="cat_not_found"} 1`,
+		`agency_http_problems_total{code="breed_check_failed"} 1`,
+		`agency_breed_validation_duration_seconds_count{result="error"} 1`,
+		`agency_missions{status="assigned"} 1`,
+		`agency_missions{status="draft"} 0`,
+		`agency_idle_cats 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics are missing %s", want)
+		}
+	}
+}
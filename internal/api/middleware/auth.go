@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/auth"
+	"spy-cat-agency/internal/store"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const principalKey = "principal"
+
+// Principal returns who made the request, nil on public routes.
+func Principal(c *gin.Context) *auth.Principal {
+	p, _ := c.Get(principalKey)
+	principal, _ := p.(*auth.Principal)
+	return principal
+}
+
+// Authenticate accepts "Authorization: Bearer <jwt>" or "X-API-Key: <key>".
+func Authenticate(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal *auth.Principal
+		var err error
+
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			principal, err = authenticator.VerifyAPIKey(key)
+		} else if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			principal, err = authenticator.VerifyToken(strings.TrimSpace(token))
+		} else {
+			err = auth.ErrUnauthenticated
+		}
+
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="spy-cat-agency"`)
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.Unauthorized, "Missing or invalid credentials"))
+			return
+		}
+
+		c.Set(principalKey, principal)
+		// the store records who made the changes
+		c.Request = c.Request.WithContext(store.WithActor(c.Request.Context(), principal.Subject))
+		c.Next()
+	}
+}
+
+func RequireRole(roles ...auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := Principal(c)
+		if principal != nil {
+			for _, role := range roles {
+				if principal.Role == role {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.Forbidden, "Not allowed for your role"))
+	}
+}
+
+// RequireTargetAccess lets staff through and limits cats to the targets of
+// the active mission assigned to them. It must run after ExtractID("targetID").
+func RequireTargetAccess(storage store.Storage, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := Principal(c)
+		if principal == nil {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.Forbidden, "Not allowed for your role"))
+			return
+		}
+
+		if principal.Role == auth.RoleStaff {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		target, err := storage.Mission.GetTargetByID(ctx, c.GetInt64("targetID"))
+		if err != nil {
+			if !errors.Is(err, store.ErrorNotFound) {
+				Log(c, logger).Error("failed to get target for access check", "error", err)
+			}
+			problem.Abort(c, problem.From(err, problem.TargetNotFound))
+			return
+		}
+
+		mission, err := storage.Mission.GetByID(ctx, target.MissionID)
+		if err != nil {
+			Log(c, logger).Error("failed to get mission for access check", "error", err)
+			problem.Abort(c, problem.From(err, problem.MissionNotFound))
+			return
+		}
+
+		if mission.CatID == nil || *mission.CatID != principal.CatID || !mission.Status.IsActive() {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.Forbidden, "Target is not on your mission"))
+			return
+		}
+
+		c.Next()
+	}
+}
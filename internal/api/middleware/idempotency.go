@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is "true" on responses replayed for a key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// replayedHeaders are the response headers stored with the body.
+var replayedHeaders = []string{"Content-Type", ETagHeader, "Location"}
+
+// Idempotent lets clients retry a create with the same Idempotency-Key and
+// get the first response back instead of a second resource. The response is
+// kept for ttl, a different request under the same key is rejected with 422
+// and a retry racing the first request with 409. Server errors aren't kept,
+// the next retry runs the request again. It must run after Authenticate,
+// keys belong to the caller.
+func Idempotent(storage store.Storage, ttl time.Duration, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		principal := Principal(c)
+		if key == "" || principal == nil {
+			c.Next()
+			return
+		}
+
+		if !printable(key, maxIdempotencyKeyLength) {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidIdempotencyKey, "Idempotency-Key must be up to 255 printable ASCII characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidBody, "Could not read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		req := &store.IdempotentRequest{
+			Owner:       principal.Subject,
+			Key:         key,
+			RequestHash: requestHash(c.Request.Method, c.Request.URL.RequestURI(), body),
+		}
+
+		// the outcome is stored even when the client gave up waiting
+		ctx := context.WithoutCancel(c.Request.Context())
+
+		existing, err := storage.Idempotency.Claim(ctx, req, ttl)
+		if err != nil {
+			Log(c, logger).Error("failed to claim idempotency key", "error", err)
+			problem.Abort(c, problem.From(err, ""))
+			return
+		}
+
+		if existing != nil {
+			switch {
+			case existing.RequestHash != req.RequestHash:
+				problem.Abort(c, problem.New(http.StatusUnprocessableEntity, problem.IdempotencyKeyReused, "Idempotency-Key was already used for another request"))
+			case !existing.Done():
+				problem.Abort(c, problem.New(http.StatusConflict, problem.IdempotencyKeyInUse, "A request with this Idempotency-Key is still being served, retry later"))
+			default:
+				for name, value := range existing.Header {
+					c.Header(name, value)
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.Status, existing.Header["Content-Type"], existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			if err := storage.Idempotency.Release(ctx, req); err != nil {
+				Log(c, logger).Error("failed to release idempotency key", "error", err)
+			}
+			return
+		}
+
+		req.Status = c.Writer.Status()
+		req.Body = rec.body.Bytes()
+		req.Header = map[string]string{}
+		for _, name := range replayedHeaders {
+			if value := c.Writer.Header().Get(name); value != "" {
+				req.Header[name] = value
+			}
+		}
+
+		if err := storage.Idempotency.Complete(ctx, req); err != nil {
+			Log(c, logger).Error("failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+// requestHash tells requests apart, a retry has the same method, URL and
+// body.
+func requestHash(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the response body as it is written.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
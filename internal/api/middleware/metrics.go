@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics times every request by route template and counts error responses
+// by problem code.
+func Metrics(m *metrics.Metrics, now func() time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveRequest(c.Request.Method, route, c.Writer.Status(), now().Sub(start))
+
+		if p := problem.Aborted(c); p != nil {
+			m.CountProblem(string(p.Code))
+		}
+	}
+}
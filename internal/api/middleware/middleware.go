@@ -1,25 +1,119 @@
 package middleware
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/store"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Logger() gin.HandlerFunc {
+const (
+	requestIDKey    = "requestID"
+	RequestIDHeader = "X-Request-ID"
+)
+
+// pathIDs are the ids ExtractID resolves, logged when the route has them.
+var pathIDs = []string{"catID", "missionID", "targetID", "noteID"}
+
+// RequestID returns the id Logger gave the request, empty outside of it.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Log returns logger with the request id attached, so every line written
+// while serving a request can be traced back to it.
+func Log(c *gin.Context, logger *slog.Logger) *slog.Logger {
+	if id := RequestID(c); id != "" {
+		return logger.With("request_id", id)
+	}
+	return logger
+}
+
+// Logger tags the request with the X-Request-ID header it came with, or a
+// new id, echoes it back and writes one line once the request is served.
+func Logger(logger *slog.Logger, now func() time.Time) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		start := time.Now()
+		start := now()
 
-		log.Printf("Incoming request: %s %s", c.Request.Method, c.Request.URL.Path)
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID(start)
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		// the audit log ties the changes to the request
+		c.Request = c.Request.WithContext(store.WithRequestID(c.Request.Context(), id))
 
 		c.Next()
 
-		duration := time.Since(start)
-		log.Printf("Response status: %d, Duration: %v", c.Writer.Status(), duration)
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", now().Sub(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		for _, key := range pathIDs {
+			if value, ok := c.Get(key); ok {
+				attrs = append(attrs, slog.Any(key, value))
+			}
+		}
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		Log(c, logger).LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// validRequestID accepts ids a client or proxy sent as long as they are
+// short printable ASCII, anything else could forge log lines or headers.
+func validRequestID(id string) bool {
+	return printable(id, 128)
+}
+
+// printable reports whether s is non-empty printable ASCII without spaces
+// and at most maxLen bytes long.
+func printable(s string, maxLen int) bool {
+	if s == "" || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// fallbackSeq keeps the ids newRequestID makes without randomness apart.
+var fallbackSeq atomic.Uint64
+
+// newRequestID returns 16 random bytes in hex. Before Go 1.24 rand.Read can
+// fail, the id then comes from the request start and a counter, it only has
+// to tell requests apart.
+func newRequestID(start time.Time) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint64(b, uint64(start.UnixNano()))
+		binary.BigEndian.PutUint64(b[8:], fallbackSeq.Add(1))
 	}
+	return hex.EncodeToString(b)
 }
 
 func ExtractID(key string) gin.HandlerFunc {
@@ -33,8 +127,8 @@ func ExtractID(key string) gin.HandlerFunc {
 
 		id, err := strconv.ParseInt(idParam, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
-			c.Abort()
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.InvalidID, "Invalid ID").
+				WithFields(problem.FieldError{Field: key, Message: "must be an integer"}))
 			return
 		}
 
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"spy-cat-agency/internal/api/problem"
+	"spy-cat-agency/internal/store"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ifMatchKey    = "ifMatch"
+	IfMatchHeader = "If-Match"
+	ETagHeader    = "ETag"
+)
+
+// ETag formats a resource version as a strong entity tag.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// RequireIfMatch makes the client name the version it last read, so it
+// can't overwrite a change it hasn't seen. "*" accepts any version, a tag
+// that isn't one of ours never matches.
+func RequireIfMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := strings.TrimSpace(c.GetHeader(IfMatchHeader))
+		if header == "" {
+			problem.Abort(c, problem.New(http.StatusPreconditionRequired, problem.PreconditionRequired,
+				"If-Match is required, send the ETag of the resource you read"))
+			return
+		}
+
+		version := store.AnyVersion
+		if header != "*" {
+			var ok bool
+			if version, ok = parseETag(header); !ok {
+				problem.Abort(c, problem.New(http.StatusPreconditionFailed, problem.PreconditionFailed,
+					"If-Match doesn't match the current ETag"))
+				return
+			}
+		}
+
+		c.Set(ifMatchKey, version)
+		c.Next()
+	}
+}
+
+// parseETag reads a tag written by ETag. Weak tags and lists are not ours.
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// IfMatch returns the version RequireIfMatch accepted, store.AnyVersion for
+// "*".
+func IfMatch(c *gin.Context) int64 {
+	return c.GetInt64(ifMatchKey)
+}
@@ -0,0 +1,433 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation documents one route. Query, Header, Body and response bodies are
+// sample values, their schemas are derived from the Go types by reflection.
+type Operation struct {
+	Summary string
+	Tag     string
+	Query   any
+	// Header fields are tagged header:"Name" like gin header binding.
+	Header    any
+	Body      any
+	Responses map[int]Response
+	// Public operations need no credentials, the rest inherit the document
+	// security requirement set by Document.Secure.
+	Public bool
+}
+
+type Response struct {
+	Description string
+	Body        any
+	// ContentType of Body, application/json when empty.
+	ContentType string
+	// Headers names the string headers sent with the response.
+	Headers []string
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]*PathItem  `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// SecurityRequirement maps a scheme name to its scopes.
+type SecurityRequirement map[string][]string
+
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	Summary     string                    `json:"summary,omitempty"`
+	Tags        []string                  `json:"tags,omitempty"`
+	OperationID string                    `json:"operationId"`
+	Parameters  []Parameter               `json:"parameters,omitempty"`
+	RequestBody *RequestBody              `json:"requestBody,omitempty"`
+	Responses   map[string]ResponseObject `json:"responses"`
+	// Security is an empty list on public operations, it overrides the
+	// document requirement.
+	Security *[]SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type ResponseObject struct {
+	Description string                  `json:"description"`
+	Headers     map[string]HeaderObject `json:"headers,omitempty"`
+	Content     map[string]MediaType    `json:"content,omitempty"`
+}
+
+type HeaderObject struct {
+	Schema *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Enumer is implemented by named types with a closed set of values, such as
+// statuses, so the schema can list them.
+type Enumer interface {
+	Enum() []any
+}
+
+// Key identifies a route the same way gin does, e.g. "GET /v1/cats/:catID".
+func Key(method, path string) string {
+	return method + " " + path
+}
+
+// Undocumented returns the keys of routes that have no operation in ops.
+func Undocumented(routes gin.RoutesInfo, ops map[string]Operation) []string {
+	var missing []string
+	for _, r := range routes {
+		if _, ok := ops[Key(r.Method, r.Path)]; !ok {
+			missing = append(missing, Key(r.Method, r.Path))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Build generates the document for routes. Routes without an operation in
+// ops are left out, use Undocumented to find them.
+func Build(info Info, routes gin.RoutesInfo, ops map[string]Operation) *Document {
+	b := &builder{schemas: map[string]*Schema{}}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+	}
+
+	for _, r := range routes {
+		op, ok := ops[Key(r.Method, r.Path)]
+		if !ok {
+			continue
+		}
+
+		path, params := convertPath(r.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+
+		(*item)[strings.ToLower(r.Method)] = b.operation(r, op, params)
+	}
+
+	doc.Components.Schemas = b.schemas
+	return doc
+}
+
+// Secure requires any one of schemes on every non public operation.
+func (d *Document) Secure(schemes map[string]SecurityScheme) {
+	d.Components.SecuritySchemes = schemes
+	d.Security = make([]SecurityRequirement, 0, len(schemes))
+
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		d.Security = append(d.Security, SecurityRequirement{name: {}})
+	}
+}
+
+var pathParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// convertPath turns gin ":id" segments into OpenAPI "{id}" ones. Every path
+// parameter in this API is a numeric ID.
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, Parameter{
+			Name:     m[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer", Format: "int64"},
+		})
+	}
+
+	return pathParam.ReplaceAllString(path, "{$1}"), params
+}
+
+type builder struct {
+	schemas map[string]*Schema
+}
+
+func (b *builder) operation(r gin.RouteInfo, op Operation, params []Parameter) *OperationObject {
+	obj := &OperationObject{
+		Summary:     op.Summary,
+		OperationID: operationID(r.Handler),
+		Parameters:  params,
+		Responses:   map[string]ResponseObject{},
+	}
+
+	if op.Tag != "" {
+		obj.Tags = []string{op.Tag}
+	}
+
+	if op.Public {
+		obj.Security = &[]SecurityRequirement{}
+	}
+
+	if op.Query != nil {
+		obj.Parameters = append(obj.Parameters, b.params(reflect.TypeOf(op.Query), "form", "query")...)
+	}
+
+	if op.Header != nil {
+		obj.Parameters = append(obj.Parameters, b.params(reflect.TypeOf(op.Header), "header", "header")...)
+	}
+
+	if op.Body != nil {
+		obj.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: b.schema(reflect.TypeOf(op.Body))}},
+		}
+	}
+
+	for code, resp := range op.Responses {
+		ro := ResponseObject{Description: resp.Description}
+		if ro.Description == "" {
+			ro.Description = http.StatusText(code)
+		}
+		for _, name := range resp.Headers {
+			if ro.Headers == nil {
+				ro.Headers = map[string]HeaderObject{}
+			}
+			ro.Headers[name] = HeaderObject{Schema: &Schema{Type: "string"}}
+		}
+		if resp.Body != nil {
+			contentType := resp.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			ro.Content = map[string]MediaType{contentType: {Schema: b.schema(reflect.TypeOf(resp.Body))}}
+		}
+		obj.Responses[strconv.Itoa(code)] = ro
+	}
+
+	return obj
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// operationID is the handler function name, closures returned by handler
+// constructors are named after the constructor.
+func operationID(handler string) string {
+	handler = closureSuffix.ReplaceAllString(handler, "")
+	if i := strings.LastIndex(handler, "."); i >= 0 {
+		handler = handler[i+1:]
+	}
+	return strings.TrimSuffix(handler, "-fm")
+}
+
+// params documents the fields of t tagged with tag as parameters in in.
+func (b *builder) params(t reflect.Type, tag, in string) []Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []Parameter
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		params = append(params, Parameter{
+			Name:     name,
+			In:       in,
+			Required: isRequired(f),
+			Schema:   b.schema(f.Type),
+		})
+	}
+	return params
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	rawType    = reflect.TypeOf(json.RawMessage{})
+	enumerType = reflect.TypeOf((*Enumer)(nil)).Elem()
+)
+
+func (b *builder) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer && t != timeType {
+		s := b.schema(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		nullable := *s
+		nullable.Nullable = true
+		return &nullable
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	// raw JSON is passed through as is, any object or null
+	if t == rawType {
+		return &Schema{Type: "object", Nullable: true}
+	}
+
+	var enum []any
+	if t.Implements(enumerType) {
+		enum = reflect.Zero(t).Interface().(Enumer).Enum()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string", Enum: enum}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Enum: enum}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Enum: enum}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		return b.structSchema(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema registers named structs as components and returns a reference,
+// so shared types like Cat appear once in the document.
+func (b *builder) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return b.objectSchema(t)
+	}
+
+	name := componentName(t)
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := b.schemas[name]; ok {
+		return ref
+	}
+
+	// placeholder first, so recursive types terminate
+	b.schemas[name] = &Schema{}
+	*b.schemas[name] = *b.objectSchema(t)
+
+	return ref
+}
+
+func (b *builder) objectSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.addFields(s, t)
+	return s
+}
+
+func (b *builder) addFields(s *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			b.addFields(s, f.Type)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = b.schema(f.Type)
+		if isRequired(f) && !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func isRequired(f reflect.StructField) bool {
+	for _, key := range []string{"validate", "binding"} {
+		for _, rule := range strings.Split(f.Tag.Get(key), ",") {
+			if rule == "required" || rule == "notblank" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var qualifier = regexp.MustCompile(`[A-Za-z0-9_./-]*\.`)
+
+// componentName makes a schema name out of a Go type name, generic
+// instantiations such as Page[store.Cat] become PageCat.
+func componentName(t reflect.Type) string {
+	name := qualifier.ReplaceAllString(t.Name(), "")
+	name = strings.NewReplacer("[", "", "]", "", ",", "", "*", "", " ", "").Replace(name)
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spy-cat-agency/internal/api"
+	"spy-cat-agency/internal/api/handlers"
+	"spy-cat-agency/internal/api/openapi"
+	"spy-cat-agency/internal/store"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.Mount(router, handlers.New(store.Storage{}, nil, nil, nil, nil, nil, nil))
+
+	for _, key := range openapi.Undocumented(router.Routes(), handlers.Docs) {
+		t.Errorf("route %s is not documented in handlers.Docs", key)
+	}
+}
+
+func TestNoStaleDocs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.Mount(router, handlers.New(store.Storage{}, nil, nil, nil, nil, nil, nil))
+
+	routes := map[string]bool{}
+	for _, r := range router.Routes() {
+		routes[openapi.Key(r.Method, r.Path)] = true
+	}
+
+	for key := range handlers.Docs {
+		if !routes[key] {
+			t.Errorf("handlers.Docs documents %s, but no such route is mounted", key)
+		}
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api.Mount(router, handlers.New(store.Storage{}, nil, nil, nil, nil, nil, nil))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("unexpected openapi version %q", doc.OpenAPI)
+	}
+
+	for _, name := range []string{"Cat", "Mission", "Target", "Note", "PageCat"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s is missing", name)
+		}
+	}
+
+	item, ok := doc.Paths["/v1/cats/{catID}"]
+	if !ok || (*item)["get"] == nil {
+		t.Fatal("GET /v1/cats/{catID} is missing")
+	}
+}
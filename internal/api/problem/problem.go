@@ -0,0 +1,177 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"spy-cat-agency/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Code is the stable, machine readable reason of a problem. Clients switch on
+// it, so existing codes must never change meaning.
+type Code string
+
+const (
+	InternalError Code = "internal_error"
+	InvalidID     Code = "invalid_id"
+	InvalidQuery  Code = "invalid_query"
+	InvalidBody   Code = "invalid_body"
+	// ValidationFailed problems list the offending fields in Errors.
+	ValidationFailed Code = "validation_failed"
+	InvalidCursor    Code = "invalid_cursor"
+	Conflict         Code = "conflict"
+	Unauthorized     Code = "unauthorized"
+	Forbidden        Code = "forbidden"
+	// PreconditionRequired and PreconditionFailed are about If-Match.
+	PreconditionRequired Code = "precondition_required"
+	PreconditionFailed   Code = "precondition_failed"
+	// InvalidIdempotencyKey, IdempotencyKeyReused and IdempotencyKeyInUse
+	// are about Idempotency-Key.
+	InvalidIdempotencyKey Code = "invalid_idempotency_key"
+	IdempotencyKeyReused  Code = "idempotency_key_reused"
+	IdempotencyKeyInUse   Code = "idempotency_key_in_use"
+
+	CatNotFound     Code = "cat_not_found"
+	MissionNotFound Code = "mission_not_found"
+	TargetNotFound  Code = "target_not_found"
+	NoteNotFound    Code = "note_not_found"
+
+	BreedCheckFailed   Code = "breed_check_failed"
+	CatBusy            Code = "cat_busy"
+	CatOnMission       Code = "cat_on_mission"
+	InvalidSuccessor   Code = "invalid_successor"
+	MissionHasSpy      Code = "mission_has_spy"
+	MissionHasNoSpy    Code = "mission_has_no_spy"
+	MissionFinished    Code = "mission_finished"
+	InvalidTransition  Code = "invalid_transition"
+	TargetsIncomplete  Code = "targets_incomplete"
+	TargetLimitReached Code = "target_limit_reached"
+	LastTarget         Code = "last_target"
+	TargetComplete     Code = "target_complete"
+	NotesFrozen        Code = "notes_frozen"
+	NotDeleted         Code = "not_deleted"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is the RFC 7807 error body every endpoint responds with.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   "urn:spy-cat-agency:problem:" + string(code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	return string(p.Code) + ": " + p.Detail
+}
+
+func (p *Problem) WithFields(fields ...FieldError) *Problem {
+	p.Errors = append(p.Errors, fields...)
+	return p
+}
+
+type mapping struct {
+	err    error
+	status int
+	code   Code
+	detail string
+}
+
+// mappings is the single place where store and domain errors get their HTTP
+// status and code.
+var mappings = []mapping{
+	{store.ErrInvalidCursor, http.StatusBadRequest, InvalidCursor, "Invalid cursor"},
+	{store.ErrCatBusy, http.StatusBadRequest, CatBusy, "Cannot assign mission: spy has unfinished business"},
+	{store.ErrCatOnMission, http.StatusConflict, CatOnMission, "Cat is on an active mission, finish it or hand it over with reassign_to"},
+	{store.ErrInvalidSuccessor, http.StatusUnprocessableEntity, InvalidSuccessor, "Cat to hand the mission over to must be another existing cat"},
+	{store.ErrMissionHasSpy, http.StatusBadRequest, MissionHasSpy, "Mission already has an assigned spy"},
+	{store.ErrTargetLimit, http.StatusBadRequest, TargetLimitReached, fmt.Sprintf("Maximum number of targets (%d) reached", store.MaxMissionTargets)},
+	{store.ErrLastTarget, http.StatusBadRequest, LastTarget, "Cannot delete last target"},
+	{store.ErrTargetComplete, http.StatusBadRequest, TargetComplete, "Target is already completed"},
+	{store.ErrNotesFrozen, http.StatusBadRequest, NotesFrozen, "Notes of completed targets and finished missions are frozen"},
+	{store.ErrMissionHasNoSpy, http.StatusBadRequest, MissionHasNoSpy, "Mission has no assigned spy"},
+	{store.ErrMissionFinished, http.StatusBadRequest, MissionFinished, "Mission is finished"},
+	{store.ErrIncompleteTargets, http.StatusBadRequest, TargetsIncomplete, "All targets must be completed first"},
+	{store.ErrInvalidTransition, http.StatusConflict, InvalidTransition, "Mission cannot move to this status from its current one"},
+	{store.ErrConflict, http.StatusConflict, Conflict, "Resource already exists"},
+	{store.ErrNotDeleted, http.StatusConflict, NotDeleted, "Resource is not deleted, there is nothing to restore"},
+	{store.ErrVersionMismatch, http.StatusPreconditionFailed, PreconditionFailed, "Resource was changed since it was read, fetch it again"},
+}
+
+// From maps err to a problem. store.ErrorNotFound doesn't tell which resource
+// is missing, so the caller passes the code to use for it.
+func From(err error, notFound Code) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	if errors.Is(err, store.ErrorNotFound) {
+		return New(http.StatusNotFound, notFound, notFoundDetail(notFound))
+	}
+
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return New(m.status, m.code, m.detail)
+		}
+	}
+
+	return New(http.StatusInternalServerError, InternalError, "Internal server error")
+}
+
+func notFoundDetail(code Code) string {
+	switch code {
+	case CatNotFound:
+		return "Cat not found"
+	case MissionNotFound:
+		return "Mission not found"
+	case TargetNotFound:
+		return "Target not found"
+	case NoteNotFound:
+		return "Note not found"
+	default:
+		return "Resource not found"
+	}
+}
+
+const contextKey = "problem"
+
+// Abort writes p as application/problem+json and stops the handler chain.
+func Abort(c *gin.Context, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+
+	c.Set(contextKey, p)
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// Aborted returns the problem the request was aborted with, nil when it
+// succeeded.
+func Aborted(c *gin.Context) *Problem {
+	p, _ := c.Get(contextKey)
+	problem, _ := p.(*Problem)
+	return problem
+}
@@ -6,7 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"spy-cat-agency/internal/store"
+	"spy-cat-agency/internal/health"
 	"sync"
 	"syscall"
 	"time"
@@ -14,27 +14,61 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-var App Application
-
 type Application struct {
 	Config Config
-	Store  store.Storage
 	Router *gin.Engine
+	// Health is drained on shutdown, nil when nothing probes the server.
+	Health *health.Checker
 }
 
+const (
+	StorePostgres = "postgres"
+	// StoreMemory keeps everything in process, it is seeded on start and
+	// lost on exit. Meant for demos.
+	StoreMemory = "memory"
+)
+
 type Config struct {
-	Addr         string
-	DB           DBConfig
-	WriteTimeout time.Duration
-	ReadTimeout  time.Duration
-	IdleTimeout  time.Duration
+	Addr         string        `yaml:"addr"`
+	Store        string        `yaml:"store"`
+	DB           DBConfig      `yaml:"db"`
+	Breed        BreedConfig   `yaml:"breed"`
+	Auth         AuthConfig    `yaml:"auth"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// DrainDelay is how long the server keeps serving after SIGTERM with
+	// readiness failing, so load balancers stop routing to it first.
+	DrainDelay time.Duration `yaml:"drain_delay"`
+	// IdempotencyTTL is how long create responses are kept for retries
+	// with the same Idempotency-Key.
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl"`
+	// Retention is how long deleted cats and missions can be restored
+	// before "api-server purge" removes them for good.
+	Retention time.Duration `yaml:"retention"`
 }
 
 type DBConfig struct {
-	Addr         string
-	MaxOpenConns int
-	MaxIdleConns int
-	MaxIdleTime  string
+	Addr         string `yaml:"addr"`
+	MaxOpenConns int    `yaml:"max_open_conns"`
+	MaxIdleConns int    `yaml:"max_idle_conns"`
+	MaxIdleTime  string `yaml:"max_idle_time"`
+	// AutoMigrate applies pending migrations on start.
+	AutoMigrate bool `yaml:"auto_migrate"`
+}
+
+type BreedConfig struct {
+	// Source is one of breed.SourceRemote, breed.SourceCatalog or breed.SourceFallback.
+	Source   string        `yaml:"source"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Retries  int           `yaml:"retries"`
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+type AuthConfig struct {
+	SigningKey string `yaml:"signing_key"`
+	// APIKeys holds "name:key" pairs separated by commas.
+	APIKeys string `yaml:"api_keys"`
 }
 
 func (app *Application) Run() {
@@ -60,9 +94,18 @@ func (app *Application) Run() {
 		}
 	}()
 
-	<-quit
+	sig := <-quit
 	log.Println("Shutdown signal received")
 
+	if app.Health != nil {
+		app.Health.Drain()
+	}
+	// SIGINT comes from a terminal, nobody routes traffic to it
+	if sig == syscall.SIGTERM && app.Config.DrainDelay > 0 {
+		log.Printf("Draining for %v", app.Config.DrainDelay)
+		time.Sleep(app.Config.DrainDelay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnauthenticated = errors.New("auth: missing or invalid credentials")
+
+type Role string
+
+const (
+	// RoleStaff manages cats and missions.
+	RoleStaff Role = "staff"
+	// RoleCat is a field agent, it may only work on its own mission.
+	RoleCat Role = "cat"
+)
+
+const issuer = "spy-cat-agency"
+
+type Principal struct {
+	Subject string `json:"sub"`
+	Role    Role   `json:"role"`
+	// CatID is set for RoleCat principals only.
+	CatID int64 `json:"cat_id,omitempty"`
+}
+
+type claims struct {
+	Role  Role  `json:"role"`
+	CatID int64 `json:"cat_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Authenticator verifies JWTs signed with a local HS256 key and static API
+// keys. API keys belong to services, which act as staff.
+type Authenticator struct {
+	key     []byte
+	apiKeys map[[sha256.Size]byte]string
+	now     func() time.Time
+}
+
+// MinSigningKeyLength is the shortest HS256 key New accepts.
+const MinSigningKeyLength = 32
+
+// New creates an authenticator. apiKeys maps a service name to its key.
+func New(signingKey []byte, apiKeys map[string]string) (*Authenticator, error) {
+	if len(signingKey) < MinSigningKeyLength {
+		return nil, fmt.Errorf("auth: signing key must be at least %d bytes", MinSigningKeyLength)
+	}
+
+	a := &Authenticator{
+		key:     signingKey,
+		apiKeys: make(map[[sha256.Size]byte]string, len(apiKeys)),
+		now:     time.Now,
+	}
+
+	for name, key := range apiKeys {
+		if key == "" {
+			return nil, fmt.Errorf("auth: empty API key for %q", name)
+		}
+		// keys are looked up by hash, so the lookup time says nothing about the key
+		a.apiKeys[sha256.Sum256([]byte(key))] = name
+	}
+
+	return a, nil
+}
+
+// ParseAPIKeys reads "name:key,name:key" pairs.
+func ParseAPIKeys(raw string) (map[string]string, error) {
+	keys := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, key, ok := strings.Cut(pair, ":")
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("auth: invalid API key entry %q, want name:key", pair)
+		}
+		keys[name] = key
+	}
+	return keys, nil
+}
+
+func (a *Authenticator) Issue(p Principal, ttl time.Duration) (string, error) {
+	// the subject is the audit actor and owns idempotency keys
+	if p.Subject == "" {
+		return "", errors.New("auth: principal needs a subject")
+	}
+	if p.Role != RoleStaff && p.Role != RoleCat {
+		return "", fmt.Errorf("auth: unknown role %q", p.Role)
+	}
+	if p.Role == RoleCat && p.CatID == 0 {
+		return "", errors.New("auth: cat principal needs a cat ID")
+	}
+
+	now := a.now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Role:  p.Role,
+		CatID: p.CatID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   p.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+
+	signed, err := token.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("auth: failed to sign token: %w", err)
+	}
+
+	return signed, nil
+}
+
+func (a *Authenticator) VerifyToken(raw string) (*Principal, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(raw, &c, func(*jwt.Token) (any, error) {
+		return a.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(a.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrUnauthenticated)
+	}
+
+	switch {
+	case c.Role == RoleStaff:
+	case c.Role == RoleCat && c.CatID != 0:
+	default:
+		return nil, fmt.Errorf("%w: bad role claims", ErrUnauthenticated)
+	}
+
+	return &Principal{Subject: c.Subject, Role: c.Role, CatID: c.CatID}, nil
+}
+
+func (a *Authenticator) VerifyAPIKey(key string) (*Principal, error) {
+	name, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	return &Principal{Subject: "service:" + name, Role: RoleStaff}, nil
+}
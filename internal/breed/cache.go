@@ -0,0 +1,99 @@
+package breed
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	valid     bool
+	expiresAt time.Time
+}
+
+// maxCachedMisses caps the unknown names kept, clients pick those freely.
+// Known breeds are few and always kept.
+const maxCachedMisses = 1024
+
+// Cached remembers answers of the wrapped validator for TTL. Errors are
+// never cached, so a flaky upstream is asked again on the next call.
+type Cached struct {
+	next Validator
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	misses  int
+}
+
+func NewCached(next Validator, ttl time.Duration) *Cached {
+	return &Cached{
+		next:    next,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *Cached) Validate(ctx context.Context, breed string) (bool, error) {
+	key := normalize(breed)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !c.now().Before(entry.expiresAt) {
+		c.remove(key, entry)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		return entry.valid, nil
+	}
+
+	valid, err := c.next.Validate(ctx, breed)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !valid && c.misses >= maxCachedMisses {
+		c.sweep()
+	}
+	if old, ok := c.entries[key]; ok {
+		c.remove(key, old)
+	}
+	if valid || c.misses < maxCachedMisses {
+		c.entries[key] = cacheEntry{valid: valid, expiresAt: c.now().Add(c.ttl)}
+		if !valid {
+			c.misses++
+		}
+	}
+
+	return valid, nil
+}
+
+// remove drops the entry, the caller holds the lock.
+func (c *Cached) remove(key string, entry cacheEntry) {
+	delete(c.entries, key)
+	if !entry.valid {
+		c.misses--
+	}
+}
+
+// sweep drops every expired entry, the caller holds the lock.
+func (c *Cached) sweep() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			c.remove(key, entry)
+		}
+	}
+}
+
+// Ping asks the wrapped validator, cached answers don't make it reachable.
+func (c *Cached) Ping(ctx context.Context) error {
+	return Ping(ctx, c.next)
+}
@@ -0,0 +1,51 @@
+package breed
+
+import (
+	"context"
+	"strings"
+)
+
+// Names is the bundled list of known breeds, it lets the agency run
+// without TheCatAPI.
+var Names = []string{
+	"Abyssinian", "Aegean", "American Bobtail", "American Curl", "American Ringtail",
+	"American Shorthair", "American Wirehair", "Aphrodite Giant", "Arabian Mau", "Asian",
+	"Asian Semi-longhair", "Australian Mist", "Balinese", "Bambino", "Bengal",
+	"Birman", "Bombay", "Brazilian Shorthair", "British Longhair", "British Shorthair",
+	"Burmese", "Burmilla", "California Spangled", "Chantilly-Tiffany", "Chartreux",
+	"Chausie", "Cheetoh", "Colorpoint Shorthair", "Cornish Rex", "Cymric",
+	"Cyprus", "Devon Rex", "Donskoy", "Dragon Li", "Dwelf",
+	"Egyptian Mau", "European Shorthair", "Exotic Shorthair", "Foldex", "German Rex",
+	"Havana Brown", "Highlander", "Himalayan", "Japanese Bobtail", "Javanese",
+	"Khao Manee", "Korat", "Korean Bobtail", "Kurilian Bobtail", "LaPerm",
+	"Lykoi", "Maine Coon", "Manx", "Mekong Bobtail", "Minskin",
+	"Munchkin", "Napoleon", "Nebelung", "Norwegian Forest Cat", "Ocicat",
+	"Oriental Bicolor", "Oriental Longhair", "Oriental Shorthair", "Persian", "Peterbald",
+	"Pixie-bob", "Ragamuffin", "Ragdoll",
+	"Sam Sawet", "Savannah", "Scottish Fold", "Selkirk Rex", "Serengeti",
+	"Siamese", "Siberian", "Singapura", "Snowshoe", "Sokoke",
+	"Somali", "Sphynx", "Suphalak", "Thai", "Thai Lilac",
+	"Tonkinese", "Toyger", "Turkish Angora", "Turkish Van", "Ukrainian Levkoy",
+	"York Chocolate",
+}
+
+type Catalog struct {
+	names map[string]struct{}
+}
+
+func NewCatalog(names []string) *Catalog {
+	c := &Catalog{names: make(map[string]struct{}, len(names))}
+	for _, name := range names {
+		c.names[normalize(name)] = struct{}{}
+	}
+	return c
+}
+
+func (c *Catalog) Validate(_ context.Context, breed string) (bool, error) {
+	_, ok := c.names[normalize(breed)]
+	return ok, nil
+}
+
+func normalize(breed string) string {
+	return strings.ToLower(strings.Join(strings.Fields(breed), " "))
+}
@@ -1,28 +1,129 @@
 package breed
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
+// Validator tells whether a breed exists. An error means the answer is
+// unknown, not that the breed is invalid.
+type Validator interface {
+	Validate(ctx context.Context, breed string) (bool, error)
+}
+
+// Pinger is implemented by validators that rely on something which can be
+// down, like a remote API.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping reports whether v can answer right now, validators that need nothing
+// outside the process always can.
+func Ping(ctx context.Context, v Validator) error {
+	if p, ok := v.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
 type CatBreedResponse struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 }
 
-func ValidateCatBreed(breed string) (bool, error) {
-	breed = strings.ReplaceAll(breed, " ", "%20")
+const defaultBaseURL = "https://api.thecatapi.com/v1"
 
-	url := fmt.Sprintf("https://api.thecatapi.com/v1/breeds/search?q=%s&attach_image=0", breed)
+var errRetryable = errors.New("breed: retryable response")
 
-	resp, err := http.Get(url)
+// Remote validates breeds against TheCatAPI.
+type Remote struct {
+	BaseURL string
+	Client  *http.Client
+	// Retries is the number of additional attempts after a failed request.
+	Retries int
+	Backoff time.Duration
+}
+
+func NewRemote(timeout time.Duration, retries int) *Remote {
+	return &Remote{
+		BaseURL: defaultBaseURL,
+		Client:  &http.Client{Timeout: timeout},
+		Retries: retries,
+		Backoff: 200 * time.Millisecond,
+	}
+}
+
+func (r *Remote) Validate(ctx context.Context, breed string) (bool, error) {
+	var err error
+	for attempt := 0; attempt <= r.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return false, fmt.Errorf("breed: %w", ctx.Err())
+			case <-time.After(r.Backoff * time.Duration(attempt)):
+			}
+		}
+
+		var ok bool
+		ok, err = r.search(ctx, breed)
+		if err == nil {
+			return ok, nil
+		}
+		if !errors.Is(err, errRetryable) {
+			return false, err
+		}
+	}
+
+	return false, err
+}
+
+// Ping makes a single request to TheCatAPI, retries would only hide an
+// outage from the readiness probe.
+func (r *Remote) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.BaseURL+"/breeds?limit=1", nil)
 	if err != nil {
-		return false, fmt.Errorf("breed: failed to make request to TheCatAPI: %w", err)
+		return fmt.Errorf("breed: failed to build request: %w", err)
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("breed: TheCatAPI is unreachable: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("breed: TheCatAPI responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (r *Remote) search(ctx context.Context, breed string) (bool, error) {
+	query := url.Values{}
+	query.Set("q", breed)
+	query.Set("attach_image", "0")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.BaseURL+"/breeds/search?"+query.Encode(), nil)
+	if err != nil {
+		return false, fmt.Errorf("breed: failed to build request: %w", err)
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("%w: failed to make request to TheCatAPI: %w", errRetryable, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return false, fmt.Errorf("%w: received status %d", errRetryable, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return false, fmt.Errorf("breed: received non-OK response: %d", resp.StatusCode)
 	}
@@ -32,9 +133,70 @@ func ValidateCatBreed(breed string) (bool, error) {
 		return false, fmt.Errorf("breed: failed to decode JSON response: %w", err)
 	}
 
-	if len(breeds) == 0 {
-		return false, nil
+	// search matches prefixes too, only an exact name makes a breed valid
+	for _, b := range breeds {
+		if strings.EqualFold(b.Name, strings.TrimSpace(breed)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Fallback asks Primary and turns to Secondary only when Primary can't answer.
+type Fallback struct {
+	Primary   Validator
+	Secondary Validator
+}
+
+func (f *Fallback) Validate(ctx context.Context, breed string) (bool, error) {
+	ok, err := f.Primary.Validate(ctx, breed)
+	if err == nil {
+		return ok, nil
+	}
+
+	ok, fallbackErr := f.Secondary.Validate(ctx, breed)
+	if fallbackErr != nil {
+		return false, errors.Join(err, fallbackErr)
 	}
 
-	return true, nil
+	return ok, nil
+}
+
+// Ping succeeds when either validator can answer.
+func (f *Fallback) Ping(ctx context.Context) error {
+	err := Ping(ctx, f.Primary)
+	if err == nil {
+		return nil
+	}
+
+	if fallbackErr := Ping(ctx, f.Secondary); fallbackErr != nil {
+		return errors.Join(err, fallbackErr)
+	}
+
+	return nil
+}
+
+const (
+	SourceRemote   = "remote"
+	SourceCatalog  = "catalog"
+	SourceFallback = "fallback"
+)
+
+// New builds the validator for source. Remote answers are cached for ttl and
+// the fallback source turns to the bundled catalog when TheCatAPI is down.
+func New(source string, timeout time.Duration, retries int, ttl time.Duration) (Validator, error) {
+	switch source {
+	case SourceRemote:
+		return NewCached(NewRemote(timeout, retries), ttl), nil
+	case SourceCatalog:
+		return NewCatalog(Names), nil
+	case SourceFallback:
+		return &Fallback{
+			Primary:   NewCached(NewRemote(timeout, retries), ttl),
+			Secondary: NewCatalog(Names),
+		}, nil
+	default:
+		return nil, fmt.Errorf("breed: unknown validator source %q", source)
+	}
 }
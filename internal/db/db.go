@@ -4,12 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// New opens the database with the session time zone set to UTC, the
+// TIMESTAMP columns hold UTC and the store binds times in UTC.
 func New(addr string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*sql.DB, error) {
+	addr, err := inUTC(addr)
+	if err != nil {
+		return nil, err
+	}
+
 	db, err := sql.Open("postgres", addr)
 	if err != nil {
 		return nil, fmt.Errorf("db: can't open connection: %w", err)
@@ -33,3 +42,26 @@ func New(addr string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*sql.
 
 	return db, nil
 }
+
+// inUTC adds timezone=UTC to a URL or key=value connection string, lib/pq
+// sends it as a run-time parameter. A zone set by the caller wins.
+func inUTC(addr string) (string, error) {
+	if strings.HasPrefix(addr, "postgres://") || strings.HasPrefix(addr, "postgresql://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return "", fmt.Errorf("db: can't parse address: %w", err)
+		}
+
+		q := u.Query()
+		if q.Get("timezone") == "" {
+			q.Set("timezone", "UTC")
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+
+	if strings.Contains(addr, "timezone=") {
+		return addr, nil
+	}
+	return strings.TrimSpace(addr + " timezone=UTC"), nil
+}
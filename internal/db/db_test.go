@@ -0,0 +1,25 @@
+package db
+
+import "testing"
+
+func TestInUTC(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{addr: "postgres://admin:pw@localhost/agency?sslmode=disable", want: "postgres://admin:pw@localhost/agency?sslmode=disable&timezone=UTC"},
+		{addr: "postgres://localhost/agency?timezone=Europe%2FKyiv", want: "postgres://localhost/agency?timezone=Europe%2FKyiv"},
+		{addr: "host=localhost dbname=agency", want: "host=localhost dbname=agency timezone=UTC"},
+		{addr: "host=localhost timezone=UTC", want: "host=localhost timezone=UTC"},
+	}
+
+	for _, tt := range tests {
+		got, err := inUTC(tt.addr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.addr, err)
+		}
+		if got != tt.want {
+			t.Errorf("got %q for %q, want %q", got, tt.addr, tt.want)
+		}
+	}
+}
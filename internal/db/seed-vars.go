@@ -13,29 +13,6 @@ var catNames = []string{
 	"Snowball", "Muffin", "Boots", "Piper", "Midnight",
 }
 
-var breedNames = []string{
-	"Abyssinian", "Aegean", "American Bobtail", "American Curl", "American Ringtail",
-	"American Shorthair", "American Wirehair", "Aphrodite Giant", "Arabian Mau", "Asian",
-	"Asian Semi-longhair", "Australian Mist", "Balinese", "Bambino", "Bengal",
-	"Birman", "Bombay", "Brazilian Shorthair", "British Longhair", "British Shorthair",
-	"Burmese", "Burmilla", "California Spangled", "Chantilly-Tiffany", "Chartreux",
-	"Chausie", "Cheetoh", "Colorpoint Shorthair", "Cornish Rex", "Cymric",
-	"Cyprus", "Devon Rex", "Donskoy", "Dragon Li", "Dwelf",
-	"Egyptian Mau", "European Shorthair", "Exotic Shorthair", "Foldex", "German Rex",
-	"Havana Brown", "Highlander", "Himalayan", "Japanese Bobtail", "Javanese",
-	"Khao Manee", "Korat", "Korean Bobtail", "Kurilian Bobtail", "LaPerm",
-	"Lykoi", "Maine Coon", "Manx", "Mekong Bobtail", "Minskin",
-	"Munchkin", "Napoleon", "Nebelung", "Norwegian Forest Cat", "Ocicat",
-	"Oriental Bicolor", "Oriental Longhair", "Oriental Shorthair", "Persian", "Peterbald",
-	"Pixie-bob", "Ragamuffin", "Ragdoll",
-	"Sam Sawet", "Savannah", "Scottish Fold", "Selkirk Rex", "Serengeti",
-	"Siamese", "Siberian", "Singapura", "Snowshoe", "Sokoke",
-	"Somali", "Sphynx", "Suphalak", "Thai", "Thai Lilac",
-	"Tonkinese", "Toyger", "Turkish Angora", "Turkish Van", "Ukrainian Levkoy",
-	"York Chocolate", "Abyssinian", "American Bobtail", "American Curl", "American Shorthair",
-	"American Wirehair", "Balinese", "Bengal", "Birman", "Bombay",
-}
-
 var targetNames = []string{
 	"Alexander", "Benjamin", "Charlotte", "Diana", "Ethan",
 	"Fiona", "Gabriel", "Hannah", "Isaac", "Julia",
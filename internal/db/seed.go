@@ -2,85 +2,244 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
 	"math/rand/v2"
+	"spy-cat-agency/internal/breed"
 	"spy-cat-agency/internal/store"
 )
 
-// go shows me store.Target is not a type (compiler NotAType) so i did this
-type storeTarget = store.Target
+type SeedConfig struct {
+	Cats     int
+	Missions int
+	Notes    int
+	// Seed drives every random choice, the same seed and counts give the
+	// same data.
+	Seed uint64
+	// Reset truncates every table and restarts the ids first. Only Seed
+	// honors it, SeedStorage expects an empty store.
+	Reset bool
+}
+
+// DefaultSeedConfig is the data set the demo and `make seed` start from.
+func DefaultSeedConfig() SeedConfig {
+	return SeedConfig{Cats: 100, Missions: 55, Notes: 44, Seed: 1}
+}
+
+func (cfg SeedConfig) validate() error {
+	if cfg.Cats < 0 || cfg.Missions < 0 || cfg.Notes < 0 {
+		return fmt.Errorf("db: seed counts can't be negative, got %d cats, %d missions and %d notes", cfg.Cats, cfg.Missions, cfg.Notes)
+	}
+	if cfg.Notes > 0 && cfg.Missions == 0 {
+		return errors.New("db: seed notes need at least one mission to hang on")
+	}
+	return nil
+}
+
+// seedNote points at a target by its position, ids are only known once the
+// missions are written.
+type seedNote struct {
+	mission int
+	target  int
+	note    string
+}
+
+type seedData struct {
+	cats     []store.Cat
+	missions []store.Mission
+	notes    []seedNote
+}
 
-func Seed(store store.Storage) {
-	ctx := context.Background()
+// assigned reports whether the mission at idx goes to the cat at the same
+// index, a mission takes exactly one spy so only the first cats get one.
+func (data *seedData) assigned(idx int) bool {
+	return idx < len(data.cats)
+}
+
+func generate(cfg SeedConfig) *seedData {
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))
+
+	data := &seedData{
+		cats:     generateCats(rng, cfg.Cats),
+		missions: generateMissions(rng, cfg.Missions),
+	}
+	data.notes = generateNotes(rng, cfg.Notes, data.missions)
+
+	return data
+}
 
-	cats := generateCats(100)
-	for idx, cat := range cats {
-		if err := store.Cat.Create(ctx, &cat); err != nil {
-			log.Println("Error in seed creating cat: ", err.Error())
-			return
+// Seed fills the database in a single transaction, either all the data is
+// written or none of it.
+func Seed(ctx context.Context, conn *sql.DB, cfg SeedConfig) (err error) {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	data := generate(cfg)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if cfg.Reset {
+		query := `TRUNCATE audit_log, idempotency_keys, salary_history, notes, targets, missions, cats RESTART IDENTITY CASCADE;`
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("db: failed to reset tables: %w", err)
 		}
-		cats[idx].ID = cat.ID
 	}
 
-	missions := generateMissions(55)
-	for idx, mission := range missions {
-		if err := store.Mission.Create(ctx, &mission); err != nil {
-			log.Println("Error in seed creating mission: ", err.Error())
-			return
+	// every cat starts its salary history, like store.CatStore.Create does
+	queryCat := `
+		WITH cat AS (
+			INSERT INTO cats (name, years_of_experience, breed, salary)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, salary
+		)
+		INSERT INTO salary_history (cat_id, salary, changed_by)
+		SELECT id, salary, $5
+		FROM cat
+		RETURNING cat_id;
+	`
+	for idx, cat := range data.cats {
+		err = tx.QueryRowContext(
+			ctx,
+			queryCat,
+			cat.Name,
+			cat.YearsOfExperience,
+			cat.Breed,
+			cat.Salary,
+			store.ActorFrom(ctx),
+		).Scan(&data.cats[idx].ID)
+		if err != nil {
+			return fmt.Errorf("db: failed to seed cat: %w", err)
 		}
-		missions[idx].ID = mission.ID
-		missions[idx].Targets = mission.Targets
 	}
 
-	var targets []storeTarget = nil
-	for _, mission := range missions {
-		targets = append(targets, mission.Targets...)
+	queryMission := `
+		INSERT INTO missions (cat_id, status, assigned_at)
+		VALUES ($1, $2, CASE WHEN $1::BIGINT IS NULL THEN NULL ELSE now() END)
+		RETURNING id;
+	`
+	queryTarget := `
+		INSERT INTO targets (mission_id, name, country, is_complete)
+		VALUES ($1, $2, $3, false)
+		RETURNING id;
+	`
+	for idx, mission := range data.missions {
+		var catID *int64
+		status := store.MissionDraft
+		if data.assigned(idx) {
+			catID = &data.cats[idx].ID
+			status = store.MissionAssigned
+		}
+
+		if err = tx.QueryRowContext(ctx, queryMission, catID, status).Scan(&data.missions[idx].ID); err != nil {
+			return fmt.Errorf("db: failed to seed mission: %w", err)
+		}
+
+		for tIdx, t := range mission.Targets {
+			err = tx.QueryRowContext(
+				ctx,
+				queryTarget,
+				data.missions[idx].ID,
+				t.Name,
+				t.Country,
+			).Scan(&mission.Targets[tIdx].ID)
+			if err != nil {
+				return fmt.Errorf("db: failed to seed target: %w", err)
+			}
+		}
 	}
 
-	notes := generateNotes(44, targets)
-	for _, note := range notes {
-		if err := store.Mission.AddNote(ctx, &note); err != nil {
-			log.Println("Error in seed creating notes: ", err.Error())
-			return
+	queryNote := `
+		INSERT INTO notes (target_id, note)
+		VALUES ($1, $2);
+	`
+	for _, n := range data.notes {
+		targetID := data.missions[n.mission].Targets[n.target].ID
+		if _, err = tx.ExecContext(ctx, queryNote, targetID, n.note); err != nil {
+			return fmt.Errorf("db: failed to seed note: %w", err)
 		}
 	}
 
-	missionsLen := len(missions)
-	for idx, cat := range cats {
-		missionIdx := idx % missionsLen
-		mission := missions[missionIdx]
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("db: failed to commit seed: %w", err)
+	}
+
+	return nil
+}
+
+// SeedStorage writes the same data as Seed through the store methods. It is
+// meant for the in-memory store, which has no transactions to undo a failed
+// run.
+func SeedStorage(ctx context.Context, storage store.Storage, cfg SeedConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	data := generate(cfg)
 
-		if err := store.Mission.AssignCat(ctx, cat.ID, mission.ID); err != nil {
-			log.Println("Error in seed assigning mission: ", err.Error())
-			return
+	for idx := range data.cats {
+		if err := storage.Cat.Create(ctx, &data.cats[idx]); err != nil {
+			return fmt.Errorf("db: failed to seed cat: %w", err)
 		}
 	}
+
+	for idx := range data.missions {
+		mission := &data.missions[idx]
+		if err := storage.Mission.Create(ctx, mission); err != nil {
+			return fmt.Errorf("db: failed to seed mission: %w", err)
+		}
+
+		if data.assigned(idx) {
+			if err := storage.Mission.AssignCat(ctx, data.cats[idx].ID, mission.ID, store.AnyVersion); err != nil {
+				return fmt.Errorf("db: failed to seed assignment: %w", err)
+			}
+		}
+	}
+
+	for _, n := range data.notes {
+		note := store.Note{
+			TargetID: data.missions[n.mission].Targets[n.target].ID,
+			Note:     n.note,
+		}
+		if err := storage.Mission.AddNote(ctx, &note); err != nil {
+			return fmt.Errorf("db: failed to seed note: %w", err)
+		}
+	}
+
+	return nil
 }
 
-func generateCats(num int) []store.Cat {
+func generateCats(rng *rand.Rand, num int) []store.Cat {
 	cats := make([]store.Cat, num)
 	catNamesLen := len(catNames)
-	catBreedLen := len(breedNames)
+	catBreedLen := len(breed.Names)
 	for idx := range cats {
 		catName := catNames[idx%catNamesLen] + fmt.Sprintf("_%d", idx)
-		catBreed := breedNames[idx%catBreedLen]
+		catBreed := breed.Names[idx%catBreedLen]
 		cats[idx] = store.Cat{
 			Name:              catName,
-			YearsOfExperience: rand.IntN(15),
+			YearsOfExperience: rng.IntN(15),
 			Breed:             catBreed,
-			Salary:            rand.Float64() * 10000.0,
+			Salary:            rng.Float64() * 10000.0,
 		}
 	}
 
 	return cats
 }
 
-func generateMissions(num int) []store.Mission {
+func generateMissions(rng *rand.Rand, num int) []store.Mission {
 	missions := make([]store.Mission, num)
 
 	for idx := range missions {
-		targs := generateTargets(1 + rand.IntN(2))
+		targs := generateTargets(rng, 1+rng.IntN(2))
 		missions[idx] = store.Mission{
 			Targets: targs,
 		}
@@ -89,14 +248,14 @@ func generateMissions(num int) []store.Mission {
 	return missions
 }
 
-func generateTargets(num int) []store.Target {
+func generateTargets(rng *rand.Rand, num int) []store.Target {
 	targets := make([]store.Target, num)
 	targetNamesLen := len(targetNames)
 	countriesLen := len(countries)
 
 	for idx := range targets {
-		targetName := targetNames[rand.IntN(targetNamesLen)] + fmt.Sprintf("_%d", idx)
-		country := countries[rand.IntN(countriesLen)]
+		targetName := targetNames[rng.IntN(targetNamesLen)] + fmt.Sprintf("_%d", idx)
+		country := countries[rng.IntN(countriesLen)]
 		targets[idx] = store.Target{
 			Name:    targetName,
 			Country: country,
@@ -106,17 +265,16 @@ func generateTargets(num int) []store.Target {
 	return targets
 }
 
-func generateNotes(num int, targets []store.Target) []store.Note {
-	notes := make([]store.Note, num)
-	targetsLen := len(targets)
+func generateNotes(rng *rand.Rand, num int, missions []store.Mission) []seedNote {
+	notes := make([]seedNote, num)
 	peopleNotesLen := len(peopleNotes)
 
 	for idx := range notes {
-		target := targets[rand.IntN(targetsLen)]
-		note := peopleNotes[idx%peopleNotesLen]
-		notes[idx] = store.Note{
-			TargetID: target.ID,
-			Note:     note,
+		mission := rng.IntN(len(missions))
+		notes[idx] = seedNote{
+			mission: mission,
+			target:  rng.IntN(len(missions[mission].Targets)),
+			note:    peopleNotes[idx%peopleNotesLen],
 		}
 	}
 
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"spy-cat-agency/internal/breed"
+	"spy-cat-agency/internal/store"
+	"testing"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	cfg := SeedConfig{Cats: 20, Missions: 15, Notes: 30, Seed: 42}
+
+	if a, b := generate(cfg), generate(cfg); !reflect.DeepEqual(a, b) {
+		t.Fatal("same seed generated different data")
+	}
+
+	other := cfg
+	other.Seed++
+	if reflect.DeepEqual(generate(cfg), generate(other)) {
+		t.Fatal("different seeds generated the same data")
+	}
+}
+
+func TestSeedConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SeedConfig
+		wantErr bool
+	}{
+		{name: "default", cfg: DefaultSeedConfig()},
+		{name: "empty", cfg: SeedConfig{}},
+		{name: "negative", cfg: SeedConfig{Cats: -1}, wantErr: true},
+		{name: "notes without missions", cfg: SeedConfig{Cats: 3, Notes: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSeedStorage(t *testing.T) {
+	ctx := context.Background()
+	cfg := SeedConfig{Cats: 5, Missions: 8, Notes: 12, Seed: 7}
+
+	storage := store.NewMemoryStorage()
+	if err := SeedStorage(ctx, storage, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	cats, err := storage.Cat.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cats) != cfg.Cats {
+		t.Fatalf("got %d cats, want %d", len(cats), cfg.Cats)
+	}
+
+	missions, err := storage.Mission.GetAllWithTargets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missions) != cfg.Missions {
+		t.Fatalf("got %d missions, want %d", len(missions), cfg.Missions)
+	}
+
+	var assigned, notes int
+	for _, m := range missions {
+		if m.Status == store.MissionAssigned {
+			assigned++
+		}
+		for _, target := range m.Targets {
+			targetNotes, err := storage.Mission.GetAllTargetNotes(ctx, target.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			notes += len(targetNotes)
+		}
+	}
+	if assigned != cfg.Cats {
+		t.Fatalf("got %d assigned missions, want %d", assigned, cfg.Cats)
+	}
+	if notes != cfg.Notes {
+		t.Fatalf("got %d notes, want %d", notes, cfg.Notes)
+	}
+}
+
+// TestSeedBreedsAreUnique keeps the seeded breeds evenly spread, generateCats
+// walks breed.Names in turn.
+func TestSeedBreedsAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, name := range breed.Names {
+		if seen[name] {
+			t.Fatalf("breed %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+}
@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports what is wrong with a dependency, nil when it is fine.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker answers the readiness probe. It runs every check on each probe and
+// fails for good once the server starts draining.
+type Checker struct {
+	// Schema reports the applied migration version, nil when the store has
+	// no schema.
+	Schema func(ctx context.Context) (uint, error)
+
+	timeout  time.Duration
+	mu       sync.Mutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// New creates a checker, every check gets timeout to answer.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain makes the server unready, so load balancers stop routing to it
+// before it shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+type Report struct {
+	Ready bool `json:"ready"`
+	// Checks maps each check to "ok" or what went wrong.
+	Checks map[string]string `json:"checks"`
+}
+
+// Ready runs the checks in parallel.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.Lock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.Unlock()
+
+	report := Report{Ready: true, Checks: make(map[string]string, len(checks)+1)}
+	if c.draining.Load() {
+		report.Ready = false
+		report.Checks["shutdown"] = "draining"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := nc.check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Ready = false
+				report.Checks[nc.name] = err.Error()
+				return
+			}
+			report.Checks[nc.name] = "ok"
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
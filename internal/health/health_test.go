@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name      string
+		checks    map[string]Check
+		drain     bool
+		wantReady bool
+		want      map[string]string
+	}{
+		{name: "no checks", wantReady: true, want: map[string]string{}},
+		{
+			name:      "all fine",
+			checks:    map[string]Check{"database": ok, "breed": ok},
+			wantReady: true,
+			want:      map[string]string{"database": "ok", "breed": "ok"},
+		},
+		{
+			name:   "one down",
+			checks: map[string]Check{"database": down, "breed": ok},
+			want:   map[string]string{"database": "connection refused", "breed": "ok"},
+		},
+		{
+			name:   "too slow",
+			checks: map[string]Check{"database": slow},
+			want:   map[string]string{"database": context.DeadlineExceeded.Error()},
+		},
+		{
+			name:   "draining",
+			checks: map[string]Check{"database": ok},
+			drain:  true,
+			want:   map[string]string{"database": "ok", "shutdown": "draining"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(10 * time.Millisecond)
+			for name, check := range tt.checks {
+				c.Add(name, check)
+			}
+			if tt.drain {
+				c.Drain()
+			}
+
+			report := c.Ready(context.Background())
+			if report.Ready != tt.wantReady {
+				t.Errorf("Ready = %v, want %v", report.Ready, tt.wantReady)
+			}
+			if len(report.Checks) != len(tt.want) {
+				t.Fatalf("checks = %v, want %v", report.Checks, tt.want)
+			}
+			for name, want := range tt.want {
+				if report.Checks[name] != want {
+					t.Errorf("%s = %q, want %q", name, report.Checks[name], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"spy-cat-agency/internal/breed"
+	"spy-cat-agency/internal/store"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "agency"
+
+// Metrics holds everything the server exports on /metrics. Each Metrics has
+// its own registry, so several servers can run in one process.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.HistogramVec
+	problems *prometheus.CounterVec
+	breed    *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time spent serving HTTP requests, by route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		problems: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_problems_total",
+			Help:      "Error responses, by problem code.",
+		}, []string{"code"}),
+		breed: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "breed_validation_duration_seconds",
+			Help:      "Time spent validating breeds, result is valid, invalid or error.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.problems,
+		m.breed,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	m.requests.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) CountProblem(code string) {
+	m.problems.WithLabelValues(code).Inc()
+}
+
+// RegisterDB exports the pool stats of db, name tells pools apart.
+func (m *Metrics) RegisterDB(name string, db *sql.DB) error {
+	if err := m.registry.Register(collectors.NewDBStatsCollector(db, name)); err != nil {
+		return fmt.Errorf("metrics: failed to register db %s: %w", name, err)
+	}
+	return nil
+}
+
+// RegisterStore exports business gauges read from storage on every scrape.
+func (m *Metrics) RegisterStore(storage store.Storage) error {
+	if err := m.registry.Register(newStoreCollector(storage)); err != nil {
+		return fmt.Errorf("metrics: failed to register store: %w", err)
+	}
+	return nil
+}
+
+// InstrumentBreed wraps v so every validation is timed and counted by result.
+func (m *Metrics) InstrumentBreed(v breed.Validator) breed.Validator {
+	return &instrumentedBreed{next: v, duration: m.breed}
+}
+
+type instrumentedBreed struct {
+	next     breed.Validator
+	duration *prometheus.HistogramVec
+}
+
+func (ib *instrumentedBreed) Validate(ctx context.Context, name string) (bool, error) {
+	start := time.Now()
+	ok, err := ib.next.Validate(ctx, name)
+
+	result := "valid"
+	switch {
+	case err != nil:
+		result = "error"
+	case !ok:
+		result = "invalid"
+	}
+	ib.duration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+
+	return ok, err
+}
+
+func (ib *instrumentedBreed) Ping(ctx context.Context) error {
+	return breed.Ping(ctx, ib.next)
+}
+
+// storeCollector reads the gauges on scrape, so they never go stale and
+// cost nothing between scrapes.
+type storeCollector struct {
+	storage  store.Storage
+	missions *prometheus.Desc
+	idleCats *prometheus.Desc
+}
+
+func newStoreCollector(storage store.Storage) *storeCollector {
+	return &storeCollector{
+		storage: storage,
+		missions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "missions"),
+			"Missions by status.",
+			[]string{"status"}, nil,
+		),
+		idleCats: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "idle_cats"),
+			"Cats without an active mission.",
+			nil, nil,
+		),
+	}
+}
+
+func (sc *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sc.missions
+	ch <- sc.idleCats
+}
+
+func (sc *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+
+	counts, err := sc.storage.Mission.CountByStatus(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(sc.missions, err)
+	} else {
+		for _, status := range store.MissionStatus("").Enum() {
+			status := status.(store.MissionStatus)
+			ch <- prometheus.MustNewConstMetric(sc.missions, prometheus.GaugeValue, float64(counts[status]), string(status))
+		}
+	}
+
+	idle, err := sc.storage.Cat.CountIdle(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(sc.idleCats, err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(sc.idleCats, prometheus.GaugeValue, float64(idle))
+	}
+}
@@ -0,0 +1,341 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+var (
+	ErrDirty          = errors.New("migrate: database is dirty, fix the schema by hand and force a version")
+	ErrUnknownVersion = errors.New("migrate: unknown version")
+)
+
+// lockID is the Postgres advisory lock held while migrating, so servers
+// starting side by side don't apply the same migration twice.
+const lockID = 4_118_905_312
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%06d_%s", m.Version, m.Name)
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load reads NNNNNN_name.up.sql and NNNNNN_name.down.sql pairs from the root
+// of fsys, ordered by version. Every migration needs both files.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: failed to read migrations: %w", err)
+	}
+
+	type pair struct {
+		Migration
+		hasUp, hasDown bool
+	}
+
+	byVersion := map[uint]*pair{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migrate: invalid version in %s", entry.Name())
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: failed to read %s: %w", entry.Name(), err)
+		}
+
+		p, ok := byVersion[uint(version)]
+		if !ok {
+			p = &pair{Migration: Migration{Version: uint(version), Name: match[2]}}
+			byVersion[p.Version] = p
+		}
+		if p.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by %s and %s", version, p.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			p.Up, p.hasUp = string(body), true
+		} else {
+			p.Down, p.hasDown = string(body), true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, p := range byVersion {
+		if !p.hasUp || !p.hasDown {
+			return nil, fmt.Errorf("migrate: %s needs both an up and a down file", p.Migration)
+		}
+		migrations = append(migrations, p.Migration)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// Migrator applies migrations and records the schema version in the
+// schema_migrations table, the same table golang-migrate uses, so databases
+// migrated by the CLI carry on where they were.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+type Status struct {
+	// Version is the applied version, 0 on an empty database.
+	Version uint
+	Dirty   bool
+	Latest  uint
+	Pending []Migration
+}
+
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	status := &Status{Latest: m.Latest()}
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		status.Version, status.Dirty, err = readVersion(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > status.Version {
+			status.Pending = append(status.Pending, mig)
+		}
+	}
+
+	return status, nil
+}
+
+// Version reads the applied version without taking the migration lock, so
+// it is cheap enough for health probes. It fails when the database was never
+// migrated.
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	return readVersion(ctx, m.db)
+}
+
+// Latest is the version of the newest migration, 0 when there is none.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down rolls back the last steps migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("migrate: steps must be positive, got %d", steps)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	applied := slices.IndexFunc(m.migrations, func(mig Migration) bool {
+		return mig.Version == status.Version
+	})
+
+	var target uint
+	if idx := applied - steps; idx >= 0 {
+		target = m.migrations[idx].Version
+	}
+
+	return m.Goto(ctx, target)
+}
+
+// Goto migrates up or down to version, 0 rolls back everything.
+func (m *Migrator) Goto(ctx context.Context, version uint) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w: version %d", ErrDirty, current)
+		}
+		if current != 0 && !m.known(current) {
+			return fmt.Errorf("%w: database is at %d", ErrUnknownVersion, current)
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version > current && mig.Version <= version {
+				if err := apply(ctx, conn, mig.Up, mig.Version); err != nil {
+					return fmt.Errorf("migrate: %s up: %w", mig, err)
+				}
+				log.Printf("migrate: applied %s", mig)
+			}
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if mig.Version <= current && mig.Version > version {
+				var previous uint
+				if i > 0 {
+					previous = m.migrations[i-1].Version
+				}
+
+				if err := apply(ctx, conn, mig.Down, previous); err != nil {
+					return fmt.Errorf("migrate: %s down: %w", mig, err)
+				}
+				log.Printf("migrate: rolled back %s", mig)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Force records version as applied and clean without running anything. It
+// is the way out of a dirty database once the schema was fixed by hand.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && !m.known(version) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return apply(ctx, conn, "", version)
+	})
+}
+
+func (m *Migrator) known(version uint) bool {
+	return slices.ContainsFunc(m.migrations, func(mig Migration) bool {
+		return mig.Version == version
+	})
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("migrate: failed to take lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		);
+	`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("migrate: failed to create version table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// queryer is a *sql.DB or a *sql.Conn.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func readVersion(ctx context.Context, conn queryer) (uint, bool, error) {
+	query := `
+		SELECT version, dirty
+		FROM schema_migrations
+		LIMIT 1;
+	`
+
+	var version int64
+	var dirty bool
+	err := conn.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("migrate: failed to read version: %w", err)
+	}
+
+	return uint(version), dirty, nil
+}
+
+// apply runs script and records version in one transaction, Postgres DDL is
+// transactional so a failed migration leaves nothing behind.
+func apply(ctx context.Context, conn *sql.Conn, script string, version uint) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if script != "" {
+		if _, err = tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations;"); err != nil {
+		return fmt.Errorf("failed to clear version: %w", err)
+	}
+
+	// golang-migrate keeps no row at all when nothing is applied
+	if version != 0 {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE);", version); err != nil {
+			return fmt.Errorf("failed to record version: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package migrate_test
+
+import (
+	"spy-cat-agency/cmd/migrate/migrations"
+	"spy-cat-agency/internal/migrate"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, m := range loaded {
+		if m.Version != uint(i+1) {
+			t.Errorf("%s: versions must be sequential, want %d", m, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("%s: up and down must both do something", m)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "ordered pairs",
+			fsys: fstest.MapFS{
+				"000002_b.up.sql":   file("B"),
+				"000002_b.down.sql": file("-B"),
+				"000001_a.up.sql":   file("A"),
+				"000001_a.down.sql": file("-A"),
+				"README.md":         file("ignored"),
+			},
+		},
+		{
+			name:    "missing down",
+			fsys:    fstest.MapFS{"000001_a.up.sql": file("A")},
+			wantErr: "needs both an up and a down file",
+		},
+		{
+			name: "one version, two names",
+			fsys: fstest.MapFS{
+				"000001_a.up.sql":   file("A"),
+				"000001_b.down.sql": file("-B"),
+			},
+			wantErr: "is used by",
+		},
+		{
+			name:    "version zero",
+			fsys:    fstest.MapFS{"000000_a.up.sql": file("A")},
+			wantErr: "invalid version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded, err := migrate.Load(tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(loaded) != 2 || loaded[0].Name != "a" || loaded[1].Up != "B" || loaded[0].Down != "-A" {
+				t.Fatalf("got %+v", loaded)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package store
+
+import "context"
+
+type actorKey struct{}
+
+type requestIDKey struct{}
+
+// SystemActor makes the changes nobody asked for through the API, like
+// seeding.
+const SystemActor = "system"
+
+// WithActor tags ctx with who makes the change, the store records it next to
+// the data it writes.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor ctx was tagged with, SystemActor when none.
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+// WithRequestID tags ctx with the id of the API request making the change,
+// the audit log keeps it to tie entries to request logs.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the request id ctx was tagged with, empty when none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
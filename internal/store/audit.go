@@ -0,0 +1,252 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	// AuditAssign puts a cat on a mission.
+	AuditAssign  AuditAction = "assign"
+	AuditRestore AuditAction = "restore"
+	// AuditPurge removes a deleted entity for good.
+	AuditPurge AuditAction = "purge"
+)
+
+// Enum lists every action, API docs use it to describe the field.
+func (AuditAction) Enum() []any {
+	return []any{AuditCreate, AuditUpdate, AuditDelete, AuditAssign, AuditRestore, AuditPurge}
+}
+
+type AuditEntity string
+
+const (
+	AuditCat     AuditEntity = "cat"
+	AuditMission AuditEntity = "mission"
+	AuditTarget  AuditEntity = "target"
+	AuditNote    AuditEntity = "note"
+)
+
+// Enum lists every entity, API docs use it to describe the field.
+func (AuditEntity) Enum() []any {
+	return []any{AuditCat, AuditMission, AuditTarget, AuditNote}
+}
+
+// AuditEntry records one change made through the store, written in the
+// same transaction as the change itself and never changed afterwards.
+type AuditEntry struct {
+	ID       int64       `json:"id"`
+	Actor    string      `json:"actor"`
+	Action   AuditAction `json:"action"`
+	Entity   AuditEntity `json:"entity"`
+	EntityID int64       `json:"entity_id"`
+	// Before and After are the entity as JSON, null when it didn't exist.
+	// Missions are recorded without their targets, except on create.
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	RequestID string          `json:"request_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows the audit log, zero fields match everything. The
+// period includes From and excludes To.
+type AuditFilter struct {
+	Actor    string
+	Action   AuditAction
+	Entity   AuditEntity
+	EntityID int64
+	From     time.Time
+	To       time.Time
+	Cursor   string
+	Limit    int
+}
+
+const auditSort = "id_desc"
+
+// newAuditEntry describes a change by the actor and request of ctx.
+// before and after are nil when the entity didn't exist on that side.
+func newAuditEntry(ctx context.Context, action AuditAction, entity AuditEntity, id int64, before, after any) (AuditEntry, error) {
+	entry := AuditEntry{
+		Actor:     ActorFrom(ctx),
+		Action:    action,
+		Entity:    entity,
+		EntityID:  id,
+		RequestID: RequestIDFrom(ctx),
+	}
+
+	var err error
+	if entry.Before, err = auditSnapshot(before); err != nil {
+		return AuditEntry{}, err
+	}
+	if entry.After, err = auditSnapshot(after); err != nil {
+		return AuditEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func auditSnapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to encode audit snapshot: %w", err)
+	}
+
+	// typed nil pointers stand for a missing entity too
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	return raw, nil
+}
+
+// recordAudit appends an entry for a change made in tx.
+func recordAudit(ctx context.Context, tx *sql.Tx, action AuditAction, entity AuditEntity, id int64, before, after any) error {
+	entry, err := newAuditEntry(ctx, action, entity, id, before, after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (actor, action, entity, entity_id, before, after, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		entry.Actor,
+		entry.Action,
+		entry.Entity,
+		entry.EntityID,
+		nullJSON(entry.Before),
+		nullJSON(entry.After),
+		entry.RequestID,
+	)
+	if err != nil {
+		return fmt.Errorf("store: failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// nullJSON passes a missing snapshot on as NULL rather than an empty value.
+func nullJSON(raw json.RawMessage) any {
+	if raw == nil {
+		return nil
+	}
+	return []byte(raw)
+}
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+// List returns one page of entries matching filter, newest first.
+func (as *AuditStore) List(ctx context.Context, filter AuditFilter) (*Page[AuditEntry], error) {
+	var conditions []string
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = "+arg(filter.Actor))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+arg(filter.Action))
+	}
+	if filter.Entity != "" {
+		conditions = append(conditions, "entity = "+arg(filter.Entity))
+	}
+	if filter.EntityID != 0 {
+		conditions = append(conditions, "entity_id = "+arg(filter.EntityID))
+	}
+	if !filter.From.IsZero() {
+		// created_at has no time zone and holds UTC since db.New pins the
+		// session zone, an offset would be dropped
+		conditions = append(conditions, "created_at >= "+arg(filter.From.UTC()))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.To.UTC()))
+	}
+	if filter.Cursor != "" {
+		id, err := decodeCursor(filter.Cursor, auditSort, nil)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "id < "+arg(id))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := normalizeLimit(filter.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT id, actor, action, entity, entity_id, before, after, request_id, created_at
+		FROM audit_log
+		%s
+		ORDER BY id DESC
+		LIMIT %s;
+	`, where, arg(limit+1))
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := as.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var before, after []byte
+		err = rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Entity, &e.EntityID, &before, &after, &e.RequestID, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to scan row: %w", err)
+		}
+
+		if before != nil {
+			e.Before = before
+		}
+		if after != nil {
+			e.After = after
+		}
+
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to iterate rows: %w", err)
+	}
+
+	page := &Page[AuditEntry]{Data: entries}
+	if len(entries) > limit {
+		page.Data = entries[:limit]
+		page.NextCursor, err = encodeCursor(auditSort, nil, page.Data[limit-1].ID)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to encode cursor: %w", err)
+		}
+	}
+
+	return page, nil
+}
@@ -5,100 +5,440 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 )
 
 type Cat struct {
 	ID                int64   `json:"id"`
-	Name              string  `json:"name"`
-	YearsOfExperience int     `json:"years_of_experience"`
-	Breed             string  `json:"breed"`
-	Salary            float64 `json:"salary"`
+	Name              string  `json:"name" validate:"notblank,max=255"`
+	YearsOfExperience int     `json:"years_of_experience" validate:"gte=0,lte=50"`
+	Breed             string  `json:"breed" validate:"notblank,max=255"`
+	Salary            float64 `json:"salary" validate:"gte=0,lte=99999999.99"`
+	Version           int64   `json:"version"`
+	// DeletedAt is only set on deleted cats, which lists show on request.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
+type CatFilter struct {
+	Breed         string
+	MinExperience *int
+	MaxExperience *int
+	MinSalary     *float64
+	MaxSalary     *float64
+	// Sort is one of CatSortFields, empty means sort by id.
+	Sort   string
+	Desc   bool
+	Cursor string
+	Limit  int
+	// IncludeDeleted lists deleted cats too.
+	IncludeDeleted bool
+}
+
+const (
+	CatSortID         = "id"
+	CatSortName       = "name"
+	CatSortExperience = "years_of_experience"
+	CatSortSalary     = "salary"
+)
+
+// CatSortFields lists the columns cats can be ordered by.
+var CatSortFields = []string{CatSortID, CatSortName, CatSortExperience, CatSortSalary}
+
 type CatStore struct {
 	db *sql.DB
 }
 
-func (cs *CatStore) Create(ctx context.Context, cat *Cat) error {
+// Create inserts the cat together with its first salary history entry.
+func (cs *CatStore) Create(ctx context.Context, cat *Cat) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
 	query := `
 		INSERT INTO cats (name, years_of_experience, breed, salary)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id;
+		RETURNING id, version;
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := cs.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		cat.Name,
 		cat.YearsOfExperience,
 		cat.Breed,
 		cat.Salary,
-	).Scan(&cat.ID)
+	).Scan(&cat.ID, &cat.Version)
 
 	if err != nil {
 		return fmt.Errorf("store: failed to create cat: %w", err)
 	}
 
+	if err = recordSalary(ctx, tx, cat.ID, cat.Salary, ActorFrom(ctx)); err != nil {
+		return err
+	}
+
+	if err = recordAudit(ctx, tx, AuditCreate, AuditCat, cat.ID, nil, cat); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (cs *CatStore) Delete(ctx context.Context, id int64) error {
-	query := `
-		DELETE FROM cats
-		WHERE id = $1;
-	`
+// Delete marks the cat at version, see AnyVersion, as deleted. A cat on an
+// active mission is ErrCatOnMission, see Retire. Finished missions keep it as
+// their spy and Purge removes the row for good.
+func (cs *CatStore) Delete(ctx context.Context, id int64, version int64) error {
+	return cs.Retire(ctx, id, version, 0)
+}
+
+// Retire deletes the cat like Delete, handing its active mission over to the
+// successor in the same transaction first. The successor must be another
+// live cat, ErrInvalidSuccessor otherwise, and idle, ErrCatBusy otherwise.
+// 0 means there is none.
+func (cs *CatStore) Retire(ctx context.Context, id int64, version int64, successorID int64) (err error) {
+	if successorID == id {
+		return ErrInvalidSuccessor
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := cs.db.ExecContext(ctx, query, id)
+	tx, err := cs.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// lock both cats in id order, so two cats retiring in favour of each
+	// other can't deadlock
+	var before *Cat
+	if successorID != 0 && successorID < id {
+		if _, err = lockSuccessor(ctx, tx, successorID); err != nil {
+			return err
+		}
+	}
+	if before, err = lockCat(ctx, tx, id); err != nil {
+		return err
+	}
+	if successorID != 0 && successorID > id {
+		if _, err = lockSuccessor(ctx, tx, successorID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE cats
+		SET deleted_at = now(), version = version + 1
+		WHERE id = $1 AND ($2::BIGINT = 0 OR version = $2);
+	`
+
+	var res sql.Result
+	if res, err = tx.ExecContext(ctx, query, id, version); err != nil {
 		return fmt.Errorf("store: failed to delete cat: %w", err)
 	}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
 		return fmt.Errorf("store: failed to retrieve affected rows: %w", err)
 	}
 
-	if rows == 0 {
-		return ErrorNotFound
+	// the cat is locked, so it can only be at another version
+	if affected == 0 {
+		err = ErrVersionMismatch
+		return err
+	}
+
+	queryMission := `
+		SELECT id
+		FROM missions
+		WHERE cat_id = $1 AND status IN ('assigned', 'in_progress') AND deleted_at IS NULL;
+	`
+
+	var missionID int64
+	err = tx.QueryRowContext(ctx, queryMission, id).Scan(&missionID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = nil
+	case err != nil:
+		return fmt.Errorf("store: failed to find active mission: %w", err)
+	case successorID == 0:
+		err = ErrCatOnMission
+		return err
+	default:
+		if err = handOver(ctx, tx, missionID, successorID); err != nil {
+			return err
+		}
+	}
+
+	if err = recordAudit(ctx, tx, AuditDelete, AuditCat, id, before, nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (cs *CatStore) Update(ctx context.Context, cat *Cat) error {
+// lockSuccessor locks the cat taking over a mission, an unknown or deleted
+// one is ErrInvalidSuccessor rather than ErrorNotFound so it isn't taken
+// for the retiring cat.
+func lockSuccessor(ctx context.Context, tx *sql.Tx, id int64) (*Cat, error) {
+	cat, err := lockCat(ctx, tx, id)
+	if errors.Is(err, ErrorNotFound) {
+		return nil, ErrInvalidSuccessor
+	}
+	return cat, err
+}
+
+// handOver moves the locked mission to the locked successor, which must not
+// have an active mission of its own. The mission keeps its status.
+func handOver(ctx context.Context, tx *sql.Tx, missionID int64, successorID int64) error {
+	mission, err := lockMission(ctx, tx, missionID)
+	if err != nil {
+		return err
+	}
+
 	query := `
-	UPDATE cats
-	SET salary = $1
-	WHERE id = $2;
+		UPDATE missions
+		SET cat_id = $1, version = version + 1
+		WHERE id = $2
+		RETURNING version;
 	`
 
+	before := *mission
+	mission.CatID = &successorID
+	if err = tx.QueryRowContext(ctx, query, successorID, missionID).Scan(&mission.Version); err != nil {
+		// the active mission index allows one per cat
+		if isUniqueViolation(err) {
+			return ErrCatBusy
+		}
+		return fmt.Errorf("store: failed to hand over mission: %w", err)
+	}
+
+	return recordAudit(ctx, tx, AuditAssign, AuditMission, missionID, &before, mission)
+}
+
+// Restore brings back a deleted cat, it doesn't get its missions back.
+func (cs *CatStore) Restore(ctx context.Context, id int64) (cat *Cat, err error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := cs.db.ExecContext(
-		ctx,
-		query,
-		cat.Salary,
-		cat.ID,
-	)
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
 
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		SELECT deleted_at
+		FROM cats
+		WHERE id = $1
+		FOR UPDATE;
+	`
+
+	var deletedAt *time.Time
+	if err = tx.QueryRowContext(ctx, query, id).Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorNotFound
+			return nil, err
+		}
+		return nil, fmt.Errorf("store: failed to lock cat: %w", err)
+	}
+
+	if deletedAt == nil {
+		err = ErrNotDeleted
+		return nil, err
+	}
+
+	query = `
+		UPDATE cats
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1
+		RETURNING id, name, years_of_experience, breed, salary, version;
+	`
+
+	cat = &Cat{}
+	err = tx.QueryRowContext(ctx, query, id).
+		Scan(&cat.ID, &cat.Name, &cat.YearsOfExperience, &cat.Breed, &cat.Salary, &cat.Version)
 	if err != nil {
-		return fmt.Errorf("store: failed to update cat salary: %w", err)
+		return nil, fmt.Errorf("store: failed to restore cat: %w", err)
+	}
+
+	if err = recordAudit(ctx, tx, AuditRestore, AuditCat, id, nil, cat); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: failed to commit transaction: %w", err)
 	}
 
-	affected, err := res.RowsAffected()
+	return cat, nil
+}
+
+// Purge removes the cats deleted before the time for good, together with
+// their salary history. Missions they ran lose their spy.
+func (cs *CatStore) Purge(ctx context.Context, before time.Time) (purged int64, err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := cs.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("store: failed to retrieve affected rows: %w", err)
+		return 0, fmt.Errorf("store: failed to begin transaction: %w", err)
 	}
 
-	if affected == 0 {
-		return ErrorNotFound
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `
+		DELETE FROM cats
+		WHERE deleted_at < $1
+		RETURNING id, name, years_of_experience, breed, salary, version, deleted_at;
+	`
+
+	// deleted_at has no time zone and holds UTC, see db.New
+	rows, err := tx.QueryContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("store: failed to purge cats: %w", err)
+	}
+
+	var cats []Cat
+	for rows.Next() {
+		var c Cat
+		if err = rows.Scan(&c.ID, &c.Name, &c.YearsOfExperience, &c.Breed, &c.Salary, &c.Version, &c.DeletedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("store: failed to scan row: %w", err)
+		}
+		cats = append(cats, c)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("store: failed to purge cats: %w", err)
+	}
+
+	for _, c := range cats {
+		if err = recordAudit(ctx, tx, AuditPurge, AuditCat, c.ID, &c, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
+	return int64(len(cats)), nil
+}
+
+// lockCat reads the cat row and locks it until tx ends, deleted cats are
+// not found.
+func lockCat(ctx context.Context, tx *sql.Tx, id int64) (*Cat, error) {
+	query := `
+		SELECT id, name, years_of_experience, breed, salary, version
+		FROM cats
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE;
+	`
+
+	var cat Cat
+	err := tx.QueryRowContext(ctx, query, id).
+		Scan(&cat.ID, &cat.Name, &cat.YearsOfExperience, &cat.Breed, &cat.Salary, &cat.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, fmt.Errorf("store: failed to lock cat: %w", err)
+		}
+	}
+
+	return &cat, nil
+}
+
+// Update changes the salary when cat.Version matches, see AnyVersion, and
+// records the change in the salary history. Setting the salary the cat
+// already earns records nothing.
+func (cs *CatStore) Update(ctx context.Context, cat *Cat) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// the row lock keeps concurrent changes in the order they are recorded
+	var before *Cat
+	if before, err = lockCat(ctx, tx, cat.ID); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE cats
+		SET salary = $1, version = version + 1
+		WHERE id = $2 AND ($3::BIGINT = 0 OR version = $3)
+		RETURNING salary, version;
+	`
+
+	var updated float64
+	err = tx.QueryRowContext(ctx, query, cat.Salary, cat.ID, cat.Version).Scan(&updated, &cat.Version)
+	if err != nil {
+		// the row is locked, so it can only be at another version
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVersionMismatch
+		}
+		return fmt.Errorf("store: failed to update cat salary: %w", err)
+	}
+
+	if updated != before.Salary {
+		if err = recordSalary(ctx, tx, cat.ID, updated, ActorFrom(ctx)); err != nil {
+			return err
+		}
+	}
+
+	after := *before
+	after.Salary, after.Version = updated, cat.Version
+	if err = recordAudit(ctx, tx, AuditUpdate, AuditCat, cat.ID, before, &after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
 	}
 
 	return nil
@@ -106,9 +446,9 @@ func (cs *CatStore) Update(ctx context.Context, cat *Cat) error {
 
 func (cs *CatStore) GetByID(ctx context.Context, id int64) (*Cat, error) {
 	query := `
-	SELECT id, name, years_of_experience, breed, salary
+	SELECT id, name, years_of_experience, breed, salary, version
 	FROM cats
-	WHERE id = $1;
+	WHERE id = $1 AND deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -123,6 +463,7 @@ func (cs *CatStore) GetByID(ctx context.Context, id int64) (*Cat, error) {
 			&cat.YearsOfExperience,
 			&cat.Breed,
 			&cat.Salary,
+			&cat.Version,
 		)
 	if err != nil {
 		switch {
@@ -138,8 +479,9 @@ func (cs *CatStore) GetByID(ctx context.Context, id int64) (*Cat, error) {
 
 func (cs *CatStore) GetAll(ctx context.Context) ([]Cat, error) {
 	query := `
-		SELECT id, name, years_of_experience, breed, salary
-		FROM cats;
+		SELECT id, name, years_of_experience, breed, salary, version
+		FROM cats
+		WHERE deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -154,7 +496,7 @@ func (cs *CatStore) GetAll(ctx context.Context) ([]Cat, error) {
 	cats := []Cat{}
 	for rows.Next() {
 		var c Cat
-		err = rows.Scan(&c.ID, &c.Name, &c.YearsOfExperience, &c.Breed, &c.Salary)
+		err = rows.Scan(&c.ID, &c.Name, &c.YearsOfExperience, &c.Breed, &c.Salary, &c.Version)
 		if err != nil {
 			return nil, fmt.Errorf("store: failed to scan row: %w", err)
 		}
@@ -169,7 +511,7 @@ func (cs *CatStore) HasIncompleteMission(ctx context.Context, catID int64) (bool
 		SELECT EXISTS (
 			SELECT 1
 			FROM missions
-			WHERE cat_id = $1 AND is_complete = FALSE
+			WHERE cat_id = $1 AND status IN ('assigned', 'in_progress') AND deleted_at IS NULL
 			LIMIT 1
 		);
 	`
@@ -185,3 +527,165 @@ func (cs *CatStore) HasIncompleteMission(ctx context.Context, catID int64) (bool
 
 	return exists, nil
 }
+
+// CountIdle counts the cats without an active mission.
+func (cs *CatStore) CountIdle(ctx context.Context) (int, error) {
+	query := `
+		SELECT count(*)
+		FROM cats c
+		WHERE c.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1
+			FROM missions
+			WHERE cat_id = c.id AND status IN ('assigned', 'in_progress') AND deleted_at IS NULL
+		);
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := cs.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("store: failed to count idle cats: %w", err)
+	}
+
+	return count, nil
+}
+
+// List returns one page of cats matching filter, ordered by filter.Sort with
+// id as a tie breaker so the keyset cursor is always unique.
+func (cs *CatStore) List(ctx context.Context, filter CatFilter) (*Page[Cat], error) {
+	if filter.Sort == "" {
+		filter.Sort = CatSortID
+	}
+	if !slices.Contains(CatSortFields, filter.Sort) {
+		return nil, fmt.Errorf("store: unknown cat sort field %q", filter.Sort)
+	}
+
+	var conditions []string
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if filter.Breed != "" {
+		conditions = append(conditions, "breed = "+arg(filter.Breed))
+	}
+	if filter.MinExperience != nil {
+		conditions = append(conditions, "years_of_experience >= "+arg(*filter.MinExperience))
+	}
+	if filter.MaxExperience != nil {
+		conditions = append(conditions, "years_of_experience <= "+arg(*filter.MaxExperience))
+	}
+	if filter.MinSalary != nil {
+		conditions = append(conditions, "salary >= "+arg(*filter.MinSalary))
+	}
+	if filter.MaxSalary != nil {
+		conditions = append(conditions, "salary <= "+arg(*filter.MaxSalary))
+	}
+
+	op, direction := ">", "ASC"
+	if filter.Desc {
+		op, direction = "<", "DESC"
+	}
+
+	if filter.Cursor != "" {
+		cond, err := catCursorCondition(filter, op, arg)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := normalizeLimit(filter.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT id, name, years_of_experience, breed, salary, version, deleted_at
+		FROM cats
+		%s
+		ORDER BY %s %s, id %s
+		LIMIT %s;
+	`, where, filter.Sort, direction, direction, arg(limit+1))
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := cs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	cats := []Cat{}
+	for rows.Next() {
+		var c Cat
+		err = rows.Scan(&c.ID, &c.Name, &c.YearsOfExperience, &c.Breed, &c.Salary, &c.Version, &c.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to scan row: %w", err)
+		}
+
+		cats = append(cats, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to iterate rows: %w", err)
+	}
+
+	page := &Page[Cat]{Data: cats}
+	if len(cats) > limit {
+		page.Data = cats[:limit]
+		page.NextCursor, err = catCursor(filter.Sort, page.Data[limit-1])
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to encode cursor: %w", err)
+		}
+	}
+
+	return page, nil
+}
+
+func catCursor(sort string, cat Cat) (string, error) {
+	switch sort {
+	case CatSortName:
+		return encodeCursor(sort, cat.Name, cat.ID)
+	case CatSortExperience:
+		return encodeCursor(sort, cat.YearsOfExperience, cat.ID)
+	case CatSortSalary:
+		return encodeCursor(sort, cat.Salary, cat.ID)
+	default:
+		return encodeCursor(sort, nil, cat.ID)
+	}
+}
+
+func catCursorCondition(filter CatFilter, op string, arg func(any) string) (string, error) {
+	var value any
+	switch filter.Sort {
+	case CatSortID:
+		id, err := decodeCursor(filter.Cursor, filter.Sort, nil)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("id %s %s", op, arg(id)), nil
+	case CatSortName:
+		value = new(string)
+	case CatSortExperience:
+		value = new(int)
+	case CatSortSalary:
+		value = new(float64)
+	}
+
+	id, err := decodeCursor(filter.Cursor, filter.Sort, value)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("(%s, id) %s (%s, %s)", filter.Sort, op, arg(value), arg(id)), nil
+}
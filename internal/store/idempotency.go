@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotentRequest is a request sent with an idempotency key and, once it
+// was served, the response to replay for the same key.
+type IdempotentRequest struct {
+	// Owner is who sent the request, keys of different callers never clash.
+	Owner       string
+	Key         string
+	RequestHash string
+	// Status is 0 while the first request is being served.
+	Status    int
+	Header    map[string]string
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Done reports whether the response of the request is stored.
+func (r *IdempotentRequest) Done() bool {
+	return r.Status != 0
+}
+
+// IdempotencyClaimTimeout is how long a request may be served before its
+// key is given to a retry, in case the server died on the way.
+const IdempotencyClaimTimeout = time.Minute
+
+// claimAttempts bounds the retries of a claim racing with expiry.
+const claimAttempts = 3
+
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+// Claim records req as being served for ttl. When the owner already holds a
+// live claim on the key nothing is written and that claim is returned, the
+// caller replays or rejects the request. Expired keys and claims abandoned
+// for IdempotencyClaimTimeout are taken over.
+func (is *IdempotencyStore) Claim(ctx context.Context, req *IdempotentRequest, ttl time.Duration) (*IdempotentRequest, error) {
+	query := `
+		INSERT INTO idempotency_keys (owner, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		ON CONFLICT (owner, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status = NULL,
+			headers = NULL,
+			body = NULL,
+			created_at = now(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+			OR (idempotency_keys.status IS NULL AND idempotency_keys.created_at <= now() - make_interval(secs => $5))
+		RETURNING created_at, expires_at;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	for range claimAttempts {
+		err := is.db.QueryRowContext(
+			ctx,
+			query,
+			req.Owner,
+			req.Key,
+			req.RequestHash,
+			ttl.Seconds(),
+			IdempotencyClaimTimeout.Seconds(),
+		).Scan(&req.CreatedAt, &req.ExpiresAt)
+		if err == nil {
+			req.Status, req.Header, req.Body = 0, nil, nil
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("store: failed to claim idempotency key: %w", err)
+		}
+
+		existing, err := is.get(ctx, req.Owner, req.Key)
+		if errors.Is(err, ErrorNotFound) {
+			// expired and deleted in between, claim again
+			continue
+		}
+		return existing, err
+	}
+
+	return nil, fmt.Errorf("store: failed to claim idempotency key: gave up after %d attempts", claimAttempts)
+}
+
+func (is *IdempotencyStore) get(ctx context.Context, owner, key string) (*IdempotentRequest, error) {
+	query := `
+		SELECT owner, key, request_hash, status, headers, body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE owner = $1 AND key = $2;
+	`
+
+	var req IdempotentRequest
+	var status sql.NullInt64
+	var header []byte
+	err := is.db.QueryRowContext(ctx, query, owner, key).Scan(
+		&req.Owner,
+		&req.Key,
+		&req.RequestHash,
+		&status,
+		&header,
+		&req.Body,
+		&req.CreatedAt,
+		&req.ExpiresAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, fmt.Errorf("store: failed to get idempotency key: %w", err)
+		}
+	}
+
+	req.Status = int(status.Int64)
+	if header != nil {
+		if err := json.Unmarshal(header, &req.Header); err != nil {
+			return nil, fmt.Errorf("store: failed to decode stored headers: %w", err)
+		}
+	}
+
+	return &req, nil
+}
+
+// Complete stores the response of a claimed request. A claim that was
+// taken over meanwhile is left alone.
+func (is *IdempotencyStore) Complete(ctx context.Context, req *IdempotentRequest) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status = $4, headers = $5, body = $6
+		WHERE owner = $1 AND key = $2 AND created_at = $3 AND status IS NULL;
+	`
+
+	header, err := json.Marshal(req.Header)
+	if err != nil {
+		return fmt.Errorf("store: failed to encode headers: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if _, err := is.db.ExecContext(ctx, query, req.Owner, req.Key, req.CreatedAt, req.Status, header, req.Body); err != nil {
+		return fmt.Errorf("store: failed to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+// Release drops the claim of a request that failed, so a retry runs it
+// again.
+func (is *IdempotencyStore) Release(ctx context.Context, req *IdempotentRequest) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE owner = $1 AND key = $2 AND created_at = $3 AND status IS NULL;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if _, err := is.db.ExecContext(ctx, query, req.Owner, req.Key, req.CreatedAt); err != nil {
+		return fmt.Errorf("store: failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired drops the keys past their TTL and returns how many.
+func (is *IdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE expires_at <= now();
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := is.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("store: failed to delete expired idempotency keys: %w", err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("store: failed to delete expired idempotency keys: %w", err)
+	}
+
+	return deleted, nil
+}
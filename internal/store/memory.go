@@ -0,0 +1,505 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryDB holds the tables of the in-memory storage. A single mutex guards
+// all of them, so every method behaves like a serializable transaction.
+type memoryDB struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	cats     map[int64]Cat
+	missions map[int64]Mission
+	targets  map[int64]Target
+	notes    map[int64]Note
+	salaries map[int64]SalaryChange
+	// idempotency is keyed by owner and key, like its primary key
+	idempotency map[[2]string]IdempotentRequest
+	// audit is append-only and ordered by id
+	audit []AuditEntry
+
+	lastCatID     int64
+	lastMissionID int64
+	lastTargetID  int64
+	lastNoteID    int64
+	lastSalaryID  int64
+	lastAuditID   int64
+}
+
+// NewMemoryStorage returns a Storage that keeps everything in memory. It
+// follows the Postgres store: same errors, soft deletes, cascading purges of
+// targets and notes, salary history going with its cat, and missions losing
+// their cat when the cat is purged.
+func NewMemoryStorage() Storage {
+	db := &memoryDB{
+		now: func() time.Time {
+			// timestamp columns keep microseconds
+			return time.Now().UTC().Truncate(time.Microsecond)
+		},
+		cats:        map[int64]Cat{},
+		missions:    map[int64]Mission{},
+		targets:     map[int64]Target{},
+		notes:       map[int64]Note{},
+		salaries:    map[int64]SalaryChange{},
+		idempotency: map[[2]string]IdempotentRequest{},
+	}
+
+	return Storage{
+		Cat:         &MemoryCatStore{db},
+		Mission:     &MemoryMissionStore{db},
+		Idempotency: &MemoryIdempotencyStore{db},
+		Audit:       &MemoryAuditStore{db},
+	}
+}
+
+// sortedValues returns the rows of table ordered by id.
+func sortedValues[T any](table map[int64]T, id func(T) int64) []T {
+	rows := make([]T, 0, len(table))
+	for _, row := range table {
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b T) int {
+		return cmp.Compare(id(a), id(b))
+	})
+	return rows
+}
+
+// versionMatches reports whether a write at version may touch a row at
+// current, like the version checks of the Postgres store.
+func versionMatches(version, current int64) bool {
+	return version == AnyVersion || version == current
+}
+
+// roundSalary mimics the DECIMAL(10,2) salary column.
+func roundSalary(salary float64) float64 {
+	return math.Round(salary*100) / 100
+}
+
+type MemoryCatStore struct {
+	db *memoryDB
+}
+
+func (cs *MemoryCatStore) Create(ctx context.Context, cat *Cat) error {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	cs.db.lastCatID++
+	cat.ID = cs.db.lastCatID
+	cat.Salary = roundSalary(cat.Salary)
+	cat.Version = 1
+	cs.db.cats[cat.ID] = *cat
+	cs.db.recordSalary(cat.ID, cat.Salary, ActorFrom(ctx))
+
+	return cs.db.recordAudit(ctx, AuditCreate, AuditCat, cat.ID, nil, cat)
+}
+
+func (cs *MemoryCatStore) Delete(ctx context.Context, id int64, version int64) error {
+	return cs.Retire(ctx, id, version, 0)
+}
+
+func (cs *MemoryCatStore) Retire(ctx context.Context, id int64, version int64, successorID int64) error {
+	if successorID == id {
+		return ErrInvalidSuccessor
+	}
+
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	stored, ok := cs.db.cat(id)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if successorID != 0 {
+		if _, ok := cs.db.cat(successorID); !ok {
+			return ErrInvalidSuccessor
+		}
+	}
+
+	if !versionMatches(version, stored.Version) {
+		return ErrVersionMismatch
+	}
+
+	// check everything before touching a row, Postgres rolls back here
+	var active *Mission
+	for _, m := range cs.db.liveMissions() {
+		if m.CatID != nil && *m.CatID == id && m.Status.IsActive() {
+			active = &m
+		}
+	}
+	if active != nil {
+		if successorID == 0 {
+			return ErrCatOnMission
+		}
+		if cs.db.catIsBusy(successorID) {
+			return ErrCatBusy
+		}
+	}
+
+	before := stored
+	now := cs.db.now()
+	stored.DeletedAt = &now
+	stored.Version++
+	cs.db.cats[id] = stored
+
+	if active != nil {
+		handed := cloneMission(*active)
+		handed.CatID = &successorID
+		handed.Version++
+		cs.db.missions[handed.ID] = handed
+
+		if err := cs.db.recordAudit(ctx, AuditAssign, AuditMission, handed.ID, active, &handed); err != nil {
+			return err
+		}
+	}
+
+	return cs.db.recordAudit(ctx, AuditDelete, AuditCat, id, &before, nil)
+}
+
+func (cs *MemoryCatStore) Restore(ctx context.Context, id int64) (*Cat, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	stored, ok := cs.db.cats[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	if stored.DeletedAt == nil {
+		return nil, ErrNotDeleted
+	}
+
+	stored.DeletedAt = nil
+	stored.Version++
+	cs.db.cats[id] = stored
+
+	if err := cs.db.recordAudit(ctx, AuditRestore, AuditCat, id, nil, &stored); err != nil {
+		return nil, err
+	}
+
+	return &stored, nil
+}
+
+func (cs *MemoryCatStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	var purged int64
+	for _, cat := range sortedValues(cs.db.cats, func(c Cat) int64 { return c.ID }) {
+		if cat.DeletedAt == nil || !cat.DeletedAt.Before(before) {
+			continue
+		}
+		delete(cs.db.cats, cat.ID)
+
+		// salary_history.cat_id is ON DELETE CASCADE
+		for salaryID, h := range cs.db.salaries {
+			if h.CatID == cat.ID {
+				delete(cs.db.salaries, salaryID)
+			}
+		}
+
+		// missions.cat_id is ON DELETE SET NULL
+		for missionID, m := range cs.db.missions {
+			if m.CatID != nil && *m.CatID == cat.ID {
+				m.CatID = nil
+				cs.db.missions[missionID] = m
+			}
+		}
+
+		if err := cs.db.recordAudit(ctx, AuditPurge, AuditCat, cat.ID, &cat, nil); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (cs *MemoryCatStore) Update(ctx context.Context, cat *Cat) error {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	stored, ok := cs.db.cat(cat.ID)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if !versionMatches(cat.Version, stored.Version) {
+		return ErrVersionMismatch
+	}
+
+	salary := roundSalary(cat.Salary)
+	if salary != stored.Salary {
+		cs.db.recordSalary(cat.ID, salary, ActorFrom(ctx))
+	}
+
+	before := stored
+	stored.Salary = salary
+	stored.Version++
+	cs.db.cats[cat.ID] = stored
+	cat.Version = stored.Version
+
+	return cs.db.recordAudit(ctx, AuditUpdate, AuditCat, cat.ID, &before, &stored)
+}
+
+func (cs *MemoryCatStore) GetByID(ctx context.Context, id int64) (*Cat, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	cat, ok := cs.db.cat(id)
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return &cat, nil
+}
+
+func (cs *MemoryCatStore) GetAll(ctx context.Context) ([]Cat, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	cats := []Cat{}
+	for _, c := range sortedValues(cs.db.cats, func(c Cat) int64 { return c.ID }) {
+		if c.DeletedAt == nil {
+			cats = append(cats, c)
+		}
+	}
+
+	return cats, nil
+}
+
+func (cs *MemoryCatStore) HasIncompleteMission(ctx context.Context, catID int64) (bool, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	return cs.db.catIsBusy(catID), nil
+}
+
+func (cs *MemoryCatStore) CountIdle(ctx context.Context) (int, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	var count int
+	for id, c := range cs.db.cats {
+		if c.DeletedAt == nil && !cs.db.catIsBusy(id) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (cs *MemoryCatStore) SalaryHistory(ctx context.Context, catID int64) ([]SalaryChange, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	if _, ok := cs.db.cat(catID); !ok {
+		return nil, ErrorNotFound
+	}
+
+	return cs.db.salaryHistory(catID), nil
+}
+
+func (cs *MemoryCatStore) Payroll(ctx context.Context, from, to time.Time) ([]PayrollLine, error) {
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	var rows []payrollRow
+	for _, month := range payrollMonths(from, to) {
+		end := month.AddDate(0, 1, 0)
+		for id, cat := range cs.db.cats {
+			if cat.DeletedAt != nil && cat.DeletedAt.Before(end) {
+				continue
+			}
+
+			// the salary in effect at the end of the month
+			var current *SalaryChange
+			for _, h := range cs.db.salaryHistory(id) {
+				if h.EffectiveFrom.Before(end) {
+					current = &h
+				}
+			}
+			if current == nil {
+				continue
+			}
+
+			rows = append(rows, payrollRow{
+				month:      month,
+				breed:      cat.Breed,
+				experience: cat.YearsOfExperience,
+				cats:       1,
+				total:      current.Salary,
+			})
+		}
+	}
+
+	return groupPayroll(rows), nil
+}
+
+func (cs *MemoryCatStore) List(ctx context.Context, filter CatFilter) (*Page[Cat], error) {
+	if filter.Sort == "" {
+		filter.Sort = CatSortID
+	}
+	if !slices.Contains(CatSortFields, filter.Sort) {
+		return nil, fmt.Errorf("store: unknown cat sort field %q", filter.Sort)
+	}
+
+	var pivot *Cat
+	if filter.Cursor != "" {
+		var err error
+		pivot, err = catFromCursor(filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order := func(a, b Cat) int {
+		c := compareCats(filter.Sort, a, b)
+		if filter.Desc {
+			return -c
+		}
+		return c
+	}
+
+	cs.db.mu.Lock()
+	defer cs.db.mu.Unlock()
+
+	cats := []Cat{}
+	for _, c := range cs.db.cats {
+		if !catMatches(filter, c) || (pivot != nil && order(c, *pivot) <= 0) {
+			continue
+		}
+		cats = append(cats, c)
+	}
+	slices.SortFunc(cats, order)
+
+	limit := normalizeLimit(filter.Limit)
+
+	page := &Page[Cat]{Data: cats}
+	if len(cats) > limit {
+		var err error
+		page.Data = cats[:limit]
+		page.NextCursor, err = catCursor(filter.Sort, page.Data[limit-1])
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to encode cursor: %w", err)
+		}
+	}
+
+	return page, nil
+}
+
+func catMatches(filter CatFilter, c Cat) bool {
+	switch {
+	case !filter.IncludeDeleted && c.DeletedAt != nil:
+		return false
+	case filter.Breed != "" && c.Breed != filter.Breed:
+		return false
+	case filter.MinExperience != nil && c.YearsOfExperience < *filter.MinExperience:
+		return false
+	case filter.MaxExperience != nil && c.YearsOfExperience > *filter.MaxExperience:
+		return false
+	case filter.MinSalary != nil && c.Salary < *filter.MinSalary:
+		return false
+	case filter.MaxSalary != nil && c.Salary > *filter.MaxSalary:
+		return false
+	}
+	return true
+}
+
+// compareCats orders cats by sort with id as a tie breaker, like List does in SQL.
+func compareCats(sort string, a, b Cat) int {
+	var c int
+	switch sort {
+	case CatSortName:
+		c = strings.Compare(a.Name, b.Name)
+	case CatSortExperience:
+		c = cmp.Compare(a.YearsOfExperience, b.YearsOfExperience)
+	case CatSortSalary:
+		c = cmp.Compare(a.Salary, b.Salary)
+	}
+	if c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// catFromCursor turns the cursor back into the last cat of the previous page,
+// only the sort field and the id are set.
+func catFromCursor(filter CatFilter) (*Cat, error) {
+	var pivot Cat
+	var value any
+	switch filter.Sort {
+	case CatSortName:
+		value = &pivot.Name
+	case CatSortExperience:
+		value = &pivot.YearsOfExperience
+	case CatSortSalary:
+		value = &pivot.Salary
+	}
+
+	id, err := decodeCursor(filter.Cursor, filter.Sort, value)
+	if err != nil {
+		return nil, err
+	}
+	pivot.ID = id
+
+	return &pivot, nil
+}
+
+// cat returns the cat unless it is missing or deleted, the caller holds the
+// lock.
+func (db *memoryDB) cat(id int64) (Cat, bool) {
+	c, ok := db.cats[id]
+	if !ok || c.DeletedAt != nil {
+		return Cat{}, false
+	}
+	return c, true
+}
+
+// catIsBusy reports whether the cat runs an active mission, the caller holds
+// the lock.
+func (db *memoryDB) catIsBusy(catID int64) bool {
+	for _, m := range db.missions {
+		if m.DeletedAt == nil && m.CatID != nil && *m.CatID == catID && m.Status.IsActive() {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSalary appends to the salary history of the cat, the caller holds
+// the lock.
+func (db *memoryDB) recordSalary(catID int64, salary float64, changedBy string) {
+	db.lastSalaryID++
+	db.salaries[db.lastSalaryID] = SalaryChange{
+		ID:            db.lastSalaryID,
+		CatID:         catID,
+		Salary:        salary,
+		EffectiveFrom: db.now(),
+		ChangedBy:     changedBy,
+	}
+}
+
+// salaryHistory returns the salary changes of the cat, oldest first. The
+// caller holds the lock.
+func (db *memoryDB) salaryHistory(catID int64) []SalaryChange {
+	history := []SalaryChange{}
+	for _, h := range sortedValues(db.salaries, func(h SalaryChange) int64 { return h.ID }) {
+		if h.CatID == catID {
+			history = append(history, h)
+		}
+	}
+	slices.SortStableFunc(history, func(a, b SalaryChange) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+	return history
+}
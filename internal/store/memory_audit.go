@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"slices"
+)
+
+type MemoryAuditStore struct {
+	db *memoryDB
+}
+
+// recordAudit mirrors the Postgres recordAudit, the caller holds the lock.
+func (db *memoryDB) recordAudit(ctx context.Context, action AuditAction, entity AuditEntity, id int64, before, after any) error {
+	entry, err := newAuditEntry(ctx, action, entity, id, before, after)
+	if err != nil {
+		return err
+	}
+
+	db.lastAuditID++
+	entry.ID = db.lastAuditID
+	entry.CreatedAt = db.now()
+	db.audit = append(db.audit, entry)
+
+	return nil
+}
+
+func (as *MemoryAuditStore) List(ctx context.Context, filter AuditFilter) (*Page[AuditEntry], error) {
+	as.db.mu.Lock()
+	defer as.db.mu.Unlock()
+
+	var after int64
+	if filter.Cursor != "" {
+		id, err := decodeCursor(filter.Cursor, auditSort, nil)
+		if err != nil {
+			return nil, err
+		}
+		after = id
+	}
+
+	limit := normalizeLimit(filter.Limit)
+
+	entries := []AuditEntry{}
+	for _, e := range slices.Backward(as.db.audit) {
+		if after != 0 && e.ID >= after {
+			continue
+		}
+		if !auditMatches(filter, e) {
+			continue
+		}
+
+		e.Before, e.After = slices.Clone(e.Before), slices.Clone(e.After)
+		entries = append(entries, e)
+		if len(entries) > limit {
+			break
+		}
+	}
+
+	page := &Page[AuditEntry]{Data: entries}
+	if len(entries) > limit {
+		page.Data = entries[:limit]
+
+		var err error
+		page.NextCursor, err = encodeCursor(auditSort, nil, page.Data[limit-1].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+func auditMatches(filter AuditFilter, e AuditEntry) bool {
+	switch {
+	case filter.Actor != "" && e.Actor != filter.Actor:
+		return false
+	case filter.Action != "" && e.Action != filter.Action:
+		return false
+	case filter.Entity != "" && e.Entity != filter.Entity:
+		return false
+	case filter.EntityID != 0 && e.EntityID != filter.EntityID:
+		return false
+	case !filter.From.IsZero() && e.CreatedAt.Before(filter.From):
+		return false
+	case !filter.To.IsZero() && !e.CreatedAt.Before(filter.To):
+		return false
+	}
+	return true
+}
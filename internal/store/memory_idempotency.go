@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+type MemoryIdempotencyStore struct {
+	db *memoryDB
+}
+
+func (is *MemoryIdempotencyStore) Claim(ctx context.Context, req *IdempotentRequest, ttl time.Duration) (*IdempotentRequest, error) {
+	is.db.mu.Lock()
+	defer is.db.mu.Unlock()
+
+	now := is.db.now()
+	id := [2]string{req.Owner, req.Key}
+
+	if stored, ok := is.db.idempotency[id]; ok {
+		abandoned := !stored.Done() && !stored.CreatedAt.After(now.Add(-IdempotencyClaimTimeout))
+		if stored.ExpiresAt.After(now) && !abandoned {
+			return copyIdempotentRequest(stored), nil
+		}
+	}
+
+	req.Status, req.Header, req.Body = 0, nil, nil
+	req.CreatedAt = now
+	req.ExpiresAt = now.Add(ttl)
+	is.db.idempotency[id] = *req
+
+	return nil, nil
+}
+
+func (is *MemoryIdempotencyStore) Complete(ctx context.Context, req *IdempotentRequest) error {
+	is.db.mu.Lock()
+	defer is.db.mu.Unlock()
+
+	id := [2]string{req.Owner, req.Key}
+	stored, ok := is.db.idempotency[id]
+	if !ok || stored.Done() || !stored.CreatedAt.Equal(req.CreatedAt) {
+		return nil
+	}
+
+	stored.Status = req.Status
+	stored.Header = maps.Clone(req.Header)
+	stored.Body = slices.Clone(req.Body)
+	is.db.idempotency[id] = stored
+
+	return nil
+}
+
+func (is *MemoryIdempotencyStore) Release(ctx context.Context, req *IdempotentRequest) error {
+	is.db.mu.Lock()
+	defer is.db.mu.Unlock()
+
+	id := [2]string{req.Owner, req.Key}
+	if stored, ok := is.db.idempotency[id]; ok && !stored.Done() && stored.CreatedAt.Equal(req.CreatedAt) {
+		delete(is.db.idempotency, id)
+	}
+
+	return nil
+}
+
+func (is *MemoryIdempotencyStore) DeleteExpired(ctx context.Context) (int64, error) {
+	is.db.mu.Lock()
+	defer is.db.mu.Unlock()
+
+	now := is.db.now()
+	var deleted int64
+	for id, stored := range is.db.idempotency {
+		if !stored.ExpiresAt.After(now) {
+			delete(is.db.idempotency, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// copyIdempotentRequest keeps callers from changing the stored response.
+func copyIdempotentRequest(req IdempotentRequest) *IdempotentRequest {
+	req.Header = maps.Clone(req.Header)
+	req.Body = slices.Clone(req.Body)
+	return &req
+}
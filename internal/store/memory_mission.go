@@ -0,0 +1,694 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type MemoryMissionStore struct {
+	db *memoryDB
+}
+
+// cloneMission copies m so callers can't reach into the tables through its
+// pointers. Targets are not copied, the missions table never holds them.
+func cloneMission(m Mission) Mission {
+	if m.CatID != nil {
+		catID := *m.CatID
+		m.CatID = &catID
+	}
+	if m.AssignedAt != nil {
+		assignedAt := *m.AssignedAt
+		m.AssignedAt = &assignedAt
+	}
+	if m.CompletedAt != nil {
+		completedAt := *m.CompletedAt
+		m.CompletedAt = &completedAt
+	}
+	if m.DeletedAt != nil {
+		deletedAt := *m.DeletedAt
+		m.DeletedAt = &deletedAt
+	}
+	m.Targets = nil
+	return m
+}
+
+func (ms *MemoryMissionStore) Create(ctx context.Context, mission *Mission) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	ms.db.lastMissionID++
+	mission.ID = ms.db.lastMissionID
+	mission.Status = MissionDraft
+	mission.Version = 1
+	ms.db.missions[mission.ID] = Mission{ID: mission.ID, Status: MissionDraft, Version: 1}
+
+	for idx, t := range mission.Targets {
+		ms.db.lastTargetID++
+		ms.db.targets[ms.db.lastTargetID] = Target{
+			ID:        ms.db.lastTargetID,
+			MissionID: mission.ID,
+			Name:      t.Name,
+			Country:   t.Country,
+			Version:   1,
+		}
+
+		mission.Targets[idx].ID = ms.db.lastTargetID
+		mission.Targets[idx].MissionID = mission.ID
+		mission.Targets[idx].Version = 1
+	}
+
+	return ms.db.recordAudit(ctx, AuditCreate, AuditMission, mission.ID, nil, mission)
+}
+
+// update that doesn't updates but rather moves the mission to mission.Status
+func (ms *MemoryMissionStore) Update(ctx context.Context, mission *Mission) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	stored, ok := ms.db.mission(mission.ID)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	before := cloneMission(stored)
+	if err := ms.db.transition(mission); err != nil {
+		return err
+	}
+
+	return ms.db.recordAudit(ctx, AuditUpdate, AuditMission, mission.ID, &before, mission)
+}
+
+// transition mirrors the Postgres transition, the caller holds the lock.
+func (db *memoryDB) transition(mission *Mission) error {
+	stored, ok := db.mission(mission.ID)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if !stored.Status.CanTransitionTo(mission.Status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, stored.Status, mission.Status)
+	}
+
+	if mission.Status == MissionCompleted {
+		for _, t := range db.targets {
+			if t.MissionID == mission.ID && !t.IsComplete {
+				return ErrIncompleteTargets
+			}
+		}
+	}
+
+	if !versionMatches(mission.Version, stored.Version) {
+		return ErrVersionMismatch
+	}
+
+	now := db.now()
+	stored.Status = mission.Status
+	stored.Version++
+	switch mission.Status {
+	case MissionAssigned:
+		stored.AssignedAt = &now
+	case MissionCompleted, MissionAborted:
+		stored.CompletedAt = &now
+	}
+	db.missions[mission.ID] = stored
+
+	updated := cloneMission(stored)
+	mission.CatID = updated.CatID
+	mission.AssignedAt = updated.AssignedAt
+	mission.CompletedAt = updated.CompletedAt
+	mission.Version = updated.Version
+
+	return nil
+}
+
+func (ms *MemoryMissionStore) Delete(ctx context.Context, id int64, version int64) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	stored, ok := ms.db.mission(id)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if stored.CatID != nil || stored.Status.IsActive() {
+		return ErrMissionHasSpy
+	}
+
+	if !versionMatches(version, stored.Version) {
+		return ErrVersionMismatch
+	}
+
+	before := cloneMission(stored)
+	now := ms.db.now()
+	stored.DeletedAt = &now
+	stored.Version++
+	ms.db.missions[id] = stored
+
+	return ms.db.recordAudit(ctx, AuditDelete, AuditMission, id, &before, nil)
+}
+
+func (ms *MemoryMissionStore) Restore(ctx context.Context, id int64) (*Mission, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	stored, ok := ms.db.missions[id]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	if stored.DeletedAt == nil {
+		return nil, ErrNotDeleted
+	}
+
+	stored.DeletedAt = nil
+	stored.Version++
+	ms.db.missions[id] = stored
+
+	mission := cloneMission(stored)
+	if err := ms.db.recordAudit(ctx, AuditRestore, AuditMission, id, nil, &mission); err != nil {
+		return nil, err
+	}
+
+	return &mission, nil
+}
+
+func (ms *MemoryMissionStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	var purged int64
+	for _, m := range sortedValues(ms.db.missions, func(m Mission) int64 { return m.ID }) {
+		if m.DeletedAt == nil || !m.DeletedAt.Before(before) {
+			continue
+		}
+		delete(ms.db.missions, m.ID)
+
+		for targetID, t := range ms.db.targets {
+			if t.MissionID == m.ID {
+				ms.db.deleteTarget(targetID)
+			}
+		}
+
+		mission := cloneMission(m)
+		if err := ms.db.recordAudit(ctx, AuditPurge, AuditMission, m.ID, &mission, nil); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// mission returns the mission unless it is missing or deleted, the caller
+// holds the lock.
+func (db *memoryDB) mission(id int64) (Mission, bool) {
+	m, ok := db.missions[id]
+	if !ok || m.DeletedAt != nil {
+		return Mission{}, false
+	}
+	return m, true
+}
+
+// target returns the target unless it is missing or its mission is deleted,
+// the caller holds the lock.
+func (db *memoryDB) target(id int64) (Target, bool) {
+	t, ok := db.targets[id]
+	if !ok {
+		return Target{}, false
+	}
+	if _, ok := db.mission(t.MissionID); !ok {
+		return Target{}, false
+	}
+	return t, true
+}
+
+// note returns the note unless it is missing or sits on a target of a
+// deleted mission, the caller holds the lock.
+func (db *memoryDB) note(id int64) (Note, bool) {
+	n, ok := db.notes[id]
+	if !ok {
+		return Note{}, false
+	}
+	if _, ok := db.target(n.TargetID); !ok {
+		return Note{}, false
+	}
+	return n, true
+}
+
+// liveMissions returns the missions that aren't deleted ordered by id, the
+// caller holds the lock.
+func (db *memoryDB) liveMissions() []Mission {
+	missions := []Mission{}
+	for _, m := range sortedValues(db.missions, func(m Mission) int64 { return m.ID }) {
+		if m.DeletedAt == nil {
+			missions = append(missions, m)
+		}
+	}
+	return missions
+}
+
+// deleteTarget removes the target with its notes, the caller holds the lock.
+func (db *memoryDB) deleteTarget(id int64) {
+	delete(db.targets, id)
+
+	for noteID, n := range db.notes {
+		if n.TargetID == id {
+			delete(db.notes, noteID)
+		}
+	}
+}
+
+func (ms *MemoryMissionStore) GetByID(ctx context.Context, id int64) (*Mission, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	m, ok := ms.db.mission(id)
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	mission := cloneMission(m)
+	return &mission, nil
+}
+
+func (ms *MemoryMissionStore) GetByIDWithTargets(ctx context.Context, id int64) (*Mission, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	m, ok := ms.db.mission(id)
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	mission := ms.db.withTargets(m)
+	return &mission, nil
+}
+
+// withTargets copies m together with its targets ordered by id, the caller
+// holds the lock.
+func (db *memoryDB) withTargets(m Mission) Mission {
+	mission := cloneMission(m)
+	mission.Targets = db.missionTargets(m.ID)
+	return mission
+}
+
+func (db *memoryDB) missionTargets(missionID int64) []Target {
+	targets := []Target{}
+	for _, t := range db.targets {
+		if t.MissionID == missionID {
+			targets = append(targets, t)
+		}
+	}
+	slices.SortFunc(targets, func(a, b Target) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return targets
+}
+
+func (ms *MemoryMissionStore) GetAll(ctx context.Context) ([]Mission, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	missions := ms.db.liveMissions()
+	for i, m := range missions {
+		missions[i] = cloneMission(m)
+	}
+
+	return missions, nil
+}
+
+func (ms *MemoryMissionStore) GetAllWithTargets(ctx context.Context) ([]Mission, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	missions := ms.db.liveMissions()
+	for i, m := range missions {
+		missions[i] = ms.db.withTargets(m)
+	}
+
+	return missions, nil
+}
+
+func (ms *MemoryMissionStore) ListWithTargets(ctx context.Context, filter MissionFilter) (*Page[Mission], error) {
+	var afterID int64
+	if filter.Cursor != "" {
+		id, err := decodeCursor(filter.Cursor, missionSortID, nil)
+		if err != nil {
+			return nil, err
+		}
+		afterID = id
+	}
+
+	limit := normalizeLimit(filter.Limit)
+
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	missions := []Mission{}
+	for _, m := range sortedValues(ms.db.missions, func(m Mission) int64 { return m.ID }) {
+		if m.ID <= afterID || (!filter.IncludeDeleted && m.DeletedAt != nil) {
+			continue
+		}
+		missions = append(missions, ms.db.withTargets(m))
+	}
+
+	page := &Page[Mission]{Data: missions}
+	if len(missions) > limit {
+		var err error
+		page.Data = missions[:limit]
+		page.NextCursor, err = encodeCursor(missionSortID, nil, page.Data[limit-1].ID)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to encode cursor: %w", err)
+		}
+	}
+
+	return page, nil
+}
+
+func (ms *MemoryMissionStore) AssignCat(ctx context.Context, catID int64, missionID int64, version int64) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	if _, ok := ms.db.cat(catID); !ok {
+		return ErrorNotFound
+	}
+
+	if ms.db.catIsBusy(catID) {
+		return ErrCatBusy
+	}
+
+	stored, ok := ms.db.mission(missionID)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if stored.CatID != nil {
+		return ErrMissionHasSpy
+	}
+
+	// check the transition before touching the row, Postgres rolls back here
+	if !stored.Status.CanTransitionTo(MissionAssigned) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, stored.Status, MissionAssigned)
+	}
+	if !versionMatches(version, stored.Version) {
+		return ErrVersionMismatch
+	}
+
+	before := cloneMission(stored)
+	stored.CatID = &catID
+	ms.db.missions[missionID] = stored
+
+	assigned := &Mission{ID: missionID, Status: MissionAssigned, Version: version}
+	if err := ms.db.transition(assigned); err != nil {
+		return err
+	}
+
+	return ms.db.recordAudit(ctx, AuditAssign, AuditMission, missionID, &before, assigned)
+}
+
+func (ms *MemoryMissionStore) HasAssignedSpy(ctx context.Context, missionID int64) (bool, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	m, ok := ms.db.mission(missionID)
+	if !ok {
+		return false, ErrorNotFound
+	}
+
+	return m.CatID != nil, nil
+}
+
+func (ms *MemoryMissionStore) AddTarget(ctx context.Context, id int64, target *Target) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	mission, ok := ms.db.mission(id)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if mission.Status.IsFinal() {
+		return ErrMissionFinished
+	}
+
+	if len(ms.db.missionTargets(id)) >= MaxMissionTargets {
+		return ErrTargetLimit
+	}
+
+	ms.db.lastTargetID++
+	target.ID = ms.db.lastTargetID
+	target.MissionID = id
+	target.Version = 1
+	ms.db.targets[target.ID] = Target{
+		ID:        target.ID,
+		MissionID: id,
+		Name:      target.Name,
+		Country:   target.Country,
+		Version:   1,
+	}
+
+	mission.Version++
+	ms.db.missions[id] = mission
+
+	return ms.db.recordAudit(ctx, AuditCreate, AuditTarget, target.ID, nil, target)
+}
+
+func (ms *MemoryMissionStore) RemoveTarget(ctx context.Context, targetId int64, version int64) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	target, ok := ms.db.target(targetId)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if target.IsComplete {
+		return ErrTargetComplete
+	}
+
+	if len(ms.db.missionTargets(target.MissionID)) <= 1 {
+		return ErrLastTarget
+	}
+
+	if !versionMatches(version, target.Version) {
+		return ErrVersionMismatch
+	}
+
+	ms.db.deleteTarget(targetId)
+
+	mission := ms.db.missions[target.MissionID]
+	mission.Version++
+	ms.db.missions[target.MissionID] = mission
+
+	return ms.db.recordAudit(ctx, AuditDelete, AuditTarget, targetId, &target, nil)
+}
+
+func (ms *MemoryMissionStore) UpdateTarget(ctx context.Context, target *Target) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	stored, ok := ms.db.target(target.ID)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	mission := ms.db.missions[stored.MissionID]
+	switch {
+	case stored.IsComplete:
+		return ErrTargetComplete
+	case mission.CatID == nil:
+		return ErrMissionHasNoSpy
+	case mission.Status.IsFinal():
+		return ErrMissionFinished
+	}
+
+	if !versionMatches(target.Version, stored.Version) {
+		return ErrVersionMismatch
+	}
+
+	before := stored
+	stored.IsComplete = target.IsComplete
+	stored.Version++
+	ms.db.targets[target.ID] = stored
+	target.Version = stored.Version
+
+	return ms.db.recordAudit(ctx, AuditUpdate, AuditTarget, target.ID, &before, &stored)
+}
+
+func (ms *MemoryMissionStore) GetTargetByID(ctx context.Context, id int64) (*Target, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	target, ok := ms.db.target(id)
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return &target, nil
+}
+
+func (ms *MemoryMissionStore) GetAllMissionTargets(ctx context.Context, missionID int64) ([]Target, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	if _, ok := ms.db.mission(missionID); !ok {
+		return []Target{}, nil
+	}
+
+	return ms.db.missionTargets(missionID), nil
+}
+
+func (ms *MemoryMissionStore) GetTargetsQuantity(ctx context.Context, missionID int64) (int, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	if _, ok := ms.db.mission(missionID); !ok {
+		return 0, nil
+	}
+
+	return len(ms.db.missionTargets(missionID)), nil
+}
+
+func (ms *MemoryMissionStore) AddNote(ctx context.Context, note *Note) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	if err := ms.db.openTarget(note.TargetID); err != nil {
+		return err
+	}
+
+	ms.db.lastNoteID++
+	note.ID = ms.db.lastNoteID
+	note.CreatedAt = ms.db.now()
+	ms.db.notes[note.ID] = *note
+
+	return ms.db.recordAudit(ctx, AuditCreate, AuditNote, note.ID, nil, note)
+}
+
+func (ms *MemoryMissionStore) GetNoteByID(ctx context.Context, id int64) (*Note, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	note, ok := ms.db.note(id)
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	return &note, nil
+}
+
+func (ms *MemoryMissionStore) GetAllTargetNotes(ctx context.Context, targetID int64) ([]Note, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	if _, ok := ms.db.target(targetID); !ok {
+		return []Note{}, nil
+	}
+
+	return ms.db.targetNotes(targetID), nil
+}
+
+// openTarget makes sure notes of the target may still change, the caller
+// holds the lock.
+func (db *memoryDB) openTarget(id int64) error {
+	target, ok := db.target(id)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if target.IsComplete || db.missions[target.MissionID].Status.IsFinal() {
+		return ErrNotesFrozen
+	}
+
+	return nil
+}
+
+// targetNotes returns the notes of the target oldest first, the caller holds
+// the lock.
+func (db *memoryDB) targetNotes(targetID int64) []Note {
+	notes := []Note{}
+	for _, n := range db.notes {
+		if n.TargetID == targetID {
+			notes = append(notes, n)
+		}
+	}
+	slices.SortFunc(notes, func(a, b Note) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return notes
+}
+
+func (ms *MemoryMissionStore) GetByIDWithNotes(ctx context.Context, id int64) (*Mission, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	m, ok := ms.db.mission(id)
+	if !ok {
+		return nil, ErrorNotFound
+	}
+
+	mission := ms.db.withTargets(m)
+	for i, t := range mission.Targets {
+		mission.Targets[i].Notes = ms.db.targetNotes(t.ID)
+	}
+
+	return &mission, nil
+}
+
+func (ms *MemoryMissionStore) UpdateNote(ctx context.Context, note *Note) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	stored, ok := ms.db.note(note.ID)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if err := ms.db.openTarget(stored.TargetID); err != nil {
+		return err
+	}
+
+	before := stored
+	stored.Note = note.Note
+	ms.db.notes[note.ID] = stored
+
+	note.TargetID = stored.TargetID
+	note.CreatedAt = stored.CreatedAt
+
+	return ms.db.recordAudit(ctx, AuditUpdate, AuditNote, note.ID, &before, note)
+}
+
+func (ms *MemoryMissionStore) CountByStatus(ctx context.Context) (map[MissionStatus]int, error) {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	counts := map[MissionStatus]int{}
+	for _, m := range ms.db.liveMissions() {
+		counts[m.Status]++
+	}
+
+	return counts, nil
+}
+
+func (ms *MemoryMissionStore) RemoveNote(ctx context.Context, noteID int64) error {
+	ms.db.mu.Lock()
+	defer ms.db.mu.Unlock()
+
+	stored, ok := ms.db.note(noteID)
+	if !ok {
+		return ErrorNotFound
+	}
+
+	if err := ms.db.openTarget(stored.TargetID); err != nil {
+		return err
+	}
+	delete(ms.db.notes, noteID)
+
+	return ms.db.recordAudit(ctx, AuditDelete, AuditNote, noteID, &stored, nil)
+}
@@ -5,15 +5,49 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 )
 
 type Mission struct {
-	ID         int64    `json:"id"`
-	CatID      *int64   `json:"cat_id"`
-	IsComplete bool     `json:"is_complete"`
-	Targets    []Target `json:"targets"`
+	ID         int64         `json:"id"`
+	CatID      *int64        `json:"cat_id"`
+	Status     MissionStatus `json:"status"`
+	AssignedAt *time.Time    `json:"assigned_at"`
+	// CompletedAt is when the mission ended, completed or aborted.
+	CompletedAt *time.Time `json:"completed_at"`
+	// Version moves with the mission row and its list of targets, the
+	// targets carry their own.
+	Version int64 `json:"version"`
+	// DeletedAt is only set on deleted missions, which lists show on
+	// request.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// the max rule must match MaxMissionTargets
+	Targets []Target `json:"targets" validate:"required,min=1,max=3,dive"`
 }
 
+const MaxMissionTargets = 3
+
+var (
+	ErrCatBusy          = errors.New("store: cat already has an active mission")
+	ErrCatOnMission     = errors.New("store: cat is on an active mission")
+	ErrInvalidSuccessor = errors.New("store: successor must be another live cat")
+	ErrMissionHasSpy    = errors.New("store: mission already has an assigned spy")
+	ErrTargetLimit      = errors.New("store: mission target limit reached")
+	ErrLastTarget       = errors.New("store: cannot remove last mission target")
+	ErrTargetComplete   = errors.New("store: target is complete")
+	ErrMissionHasNoSpy  = errors.New("store: mission has no assigned spy")
+	ErrMissionFinished  = errors.New("store: mission is finished")
+)
+
+type MissionFilter struct {
+	Cursor string
+	Limit  int
+	// IncludeDeleted lists deleted missions too.
+	IncludeDeleted bool
+}
+
+const missionSortID = "id"
+
 type MissionStore struct {
 	db *sql.DB
 }
@@ -34,15 +68,15 @@ func (ms *MissionStore) Create(ctx context.Context, mission *Mission) error {
 	}()
 
 	queryCreateMission := `
-		INSERT INTO missions (cat_id, is_complete)
-		Values (NULL, false)
-		RETURNING id;
+		INSERT INTO missions (cat_id, status)
+		Values (NULL, 'draft')
+		RETURNING id, status, version;
 	`
 
 	err = tx.QueryRowContext(
 		ctx,
 		queryCreateMission,
-	).Scan(&mission.ID)
+	).Scan(&mission.ID, &mission.Status, &mission.Version)
 
 	if err != nil {
 		return fmt.Errorf("store: failed to create mission: %w", err)
@@ -51,23 +85,26 @@ func (ms *MissionStore) Create(ctx context.Context, mission *Mission) error {
 	queryCreateTargets := `
 		INSERT INTO targets (mission_id, name, country, is_complete)
 		Values ($1, $2, $3, false)
-		RETURNING id;
+		RETURNING id, version;
 	`
-	for idx, t := range mission.Targets {
-		var id int64
+	for idx := range mission.Targets {
+		t := &mission.Targets[idx]
 		err = tx.QueryRowContext(
 			ctx,
 			queryCreateTargets,
 			mission.ID,
 			t.Name,
 			t.Country,
-		).Scan(&id)
+		).Scan(&t.ID, &t.Version)
 		if err != nil {
 			return fmt.Errorf("store: failed to create target: %w", err)
 		}
 
-		mission.Targets[idx].ID = id
-		mission.Targets[idx].MissionID = mission.ID
+		t.MissionID = mission.ID
+	}
+
+	if err = recordAudit(ctx, tx, AuditCreate, AuditMission, mission.ID, nil, mission); err != nil {
+		return err
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -77,73 +114,228 @@ func (ms *MissionStore) Create(ctx context.Context, mission *Mission) error {
 	return nil
 }
 
-// update that doesn't updates but rather changes mission marking
+// update that doesn't updates but rather moves the mission to mission.Status,
+// mission.Version must match unless it is AnyVersion
 func (ms *MissionStore) Update(ctx context.Context, mission *Mission) error {
-	query := `
-	UPDATE missions
-	SET is_complete = $1
-	WHERE id = $2;
-	`
-
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := ms.db.ExecContext(
-		ctx,
-		query,
-		mission.IsComplete,
-		mission.ID,
-	)
-
+	tx, err := ms.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("store: failed to update mission: %w", err)
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var before *Mission
+	if before, err = lockMission(ctx, tx, mission.ID); err != nil {
+		return err
+	}
+
+	if err = transition(ctx, tx, mission); err != nil {
+		return err
 	}
 
-	affected, err := res.RowsAffected()
+	if err = recordAudit(ctx, tx, AuditUpdate, AuditMission, mission.ID, before, mission); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Delete marks the mission at version, see AnyVersion, as deleted. A mission
+// with a spy is ErrMissionHasSpy. Its targets and notes stay until Purge
+// removes the mission for good.
+func (ms *MissionStore) Delete(ctx context.Context, id int64, version int64) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := ms.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var before *Mission
+	if before, err = lockMission(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if before.CatID != nil || before.Status.IsActive() {
+		err = ErrMissionHasSpy
+		return err
+	}
+
+	query := `
+		UPDATE missions
+		SET deleted_at = now(), version = version + 1
+		WHERE id = $1 AND ($2::BIGINT = 0 OR version = $2);
+	`
+
+	var res sql.Result
+	if res, err = tx.ExecContext(ctx, query, id, version); err != nil {
+		return fmt.Errorf("store: failed to delete mission: %w", err)
+	}
+
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
 		return fmt.Errorf("store: failed to retrieve affected rows: %w", err)
 	}
 
+	// the mission is locked, so it can only be at another version
 	if affected == 0 {
-		return ErrorNotFound
+		err = ErrVersionMismatch
+		return err
+	}
+
+	if err = recordAudit(ctx, tx, AuditDelete, AuditMission, id, before, nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (ms *MissionStore) Delete(ctx context.Context, id int64) error {
+// Restore brings back a deleted mission with its targets and notes.
+func (ms *MissionStore) Restore(ctx context.Context, id int64) (mission *Mission, err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
 	query := `
-		DELETE FROM missions
-		WHERE id = $1;
+		SELECT deleted_at
+		FROM missions
+		WHERE id = $1
+		FOR UPDATE;
+	`
+
+	var deletedAt *time.Time
+	if err = tx.QueryRowContext(ctx, query, id).Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorNotFound
+			return nil, err
+		}
+		return nil, fmt.Errorf("store: failed to lock mission: %w", err)
+	}
+
+	if deletedAt == nil {
+		err = ErrNotDeleted
+		return nil, err
+	}
+
+	query = `
+		UPDATE missions
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1
+		RETURNING id, cat_id, status, assigned_at, completed_at, version;
 	`
 
+	mission = &Mission{}
+	err = tx.QueryRowContext(ctx, query, id).
+		Scan(&mission.ID, &mission.CatID, &mission.Status, &mission.AssignedAt, &mission.CompletedAt, &mission.Version)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to restore mission: %w", err)
+	}
+
+	if err = recordAudit(ctx, tx, AuditRestore, AuditMission, id, nil, mission); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
+	return mission, nil
+}
+
+// Purge removes the missions deleted before the time for good, their
+// targets and notes go with them.
+func (ms *MissionStore) Purge(ctx context.Context, before time.Time) (purged int64, err error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := ms.db.ExecContext(ctx, query, id)
+	tx, err := ms.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("store: failed to delete mission: %w", err)
+		return 0, fmt.Errorf("store: failed to begin transaction: %w", err)
 	}
 
-	rows, err := res.RowsAffected()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// on delete cascade will do the thing with targets and notes
+	query := `
+		DELETE FROM missions
+		WHERE deleted_at < $1
+		RETURNING id, cat_id, status, assigned_at, completed_at, version, deleted_at;
+	`
+
+	// deleted_at has no time zone and holds UTC, see db.New
+	rows, err := tx.QueryContext(ctx, query, before.UTC())
 	if err != nil {
-		return fmt.Errorf("store: failed to retrieve affected rows: %w", err)
+		return 0, fmt.Errorf("store: failed to purge missions: %w", err)
 	}
 
-	if rows == 0 {
-		return ErrorNotFound
+	var missions []Mission
+	for rows.Next() {
+		var m Mission
+		if err = rows.Scan(&m.ID, &m.CatID, &m.Status, &m.AssignedAt, &m.CompletedAt, &m.Version, &m.DeletedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("store: failed to scan row: %w", err)
+		}
+		missions = append(missions, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("store: failed to purge missions: %w", err)
 	}
 
-	// on delete cascade will do the thing with targets and notes
+	for _, m := range missions {
+		if err = recordAudit(ctx, tx, AuditPurge, AuditMission, m.ID, &m, nil); err != nil {
+			return 0, err
+		}
+	}
 
-	return nil
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
+	return int64(len(missions)), nil
 }
 
 func (ms *MissionStore) GetByID(ctx context.Context, id int64) (*Mission, error) {
 	query := `
-	SELECT id, cat_id, is_complete
+	SELECT id, cat_id, status, assigned_at, completed_at, version
 	FROM missions
-	WHERE id = $1;
+	WHERE id = $1 AND deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -154,7 +346,10 @@ func (ms *MissionStore) GetByID(ctx context.Context, id int64) (*Mission, error)
 		Scan(
 			&mission.ID,
 			&mission.CatID,
-			&mission.IsComplete,
+			&mission.Status,
+			&mission.AssignedAt,
+			&mission.CompletedAt,
+			&mission.Version,
 		)
 	if err != nil {
 		switch {
@@ -169,25 +364,41 @@ func (ms *MissionStore) GetByID(ctx context.Context, id int64) (*Mission, error)
 }
 
 func (ms *MissionStore) GetByIDWithTargets(ctx context.Context, id int64) (*Mission, error) {
-	mission, err := ms.GetByID(ctx, id)
+	query := `
+		SELECT m.id, m.cat_id, m.status, m.assigned_at, m.completed_at, m.version, m.deleted_at,
+			t.id, t.mission_id, t.name, t.country, t.is_complete, t.version
+		FROM missions m
+		LEFT JOIN targets t ON t.mission_id = m.id
+		WHERE m.id = $1 AND m.deleted_at IS NULL
+		ORDER BY t.id;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := ms.db.QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, fmt.Errorf("store: failed to get mission by ID: %w", err)
 	}
+	defer rows.Close()
 
-	targets, err := ms.GetAllMissionTargets(ctx, mission.ID)
+	missions, err := scanMissionsWithTargets(rows)
 	if err != nil {
-		return nil, fmt.Errorf("store: failed to get targets for mission %d: %w", mission.ID, err)
+		return nil, err
 	}
 
-	mission.Targets = targets
+	if len(missions) == 0 {
+		return nil, ErrorNotFound
+	}
 
-	return mission, nil
+	return &missions[0], nil
 }
 
 func (ms *MissionStore) GetAll(ctx context.Context) ([]Mission, error) {
 	query := `
-		SELECT id, cat_id, is_complete
-		FROM missions;
+		SELECT id, cat_id, status, assigned_at, completed_at, version
+		FROM missions
+		WHERE deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -202,7 +413,7 @@ func (ms *MissionStore) GetAll(ctx context.Context) ([]Mission, error) {
 	missions := []Mission{}
 	for rows.Next() {
 		var m Mission
-		err = rows.Scan(&m.ID, &m.CatID, &m.IsComplete)
+		err = rows.Scan(&m.ID, &m.CatID, &m.Status, &m.AssignedAt, &m.CompletedAt, &m.Version)
 		if err != nil {
 			return nil, fmt.Errorf("store: failed to scan row: %w", err)
 		}
@@ -213,50 +424,230 @@ func (ms *MissionStore) GetAll(ctx context.Context) ([]Mission, error) {
 }
 
 func (ms *MissionStore) GetAllWithTargets(ctx context.Context) ([]Mission, error) {
-	missions, err := ms.GetAll(ctx)
+	query := `
+		SELECT m.id, m.cat_id, m.status, m.assigned_at, m.completed_at, m.version, m.deleted_at,
+			t.id, t.mission_id, t.name, t.country, t.is_complete, t.version
+		FROM missions m
+		LEFT JOIN targets t ON t.mission_id = m.id
+		WHERE m.deleted_at IS NULL
+		ORDER BY m.id, t.id;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := ms.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("store: failed to get missions: %w", err)
 	}
+	defer rows.Close()
+
+	return scanMissionsWithTargets(rows)
+}
 
-	for i, mission := range missions {
-		targets, err := ms.GetAllMissionTargets(ctx, mission.ID)
+// ListWithTargets returns one page of missions ordered by id together with
+// their targets. The page is cut in a CTE and joined with targets, so the
+// whole page costs a single round trip.
+func (ms *MissionStore) ListWithTargets(ctx context.Context, filter MissionFilter) (*Page[Mission], error) {
+	var afterID int64
+	if filter.Cursor != "" {
+		id, err := decodeCursor(filter.Cursor, missionSortID, nil)
 		if err != nil {
-			return nil, fmt.Errorf("store: failed to get targets for mission %d: %w", mission.ID, err)
+			return nil, err
 		}
-		missions[i].Targets = targets
+		afterID = id
 	}
 
-	return missions, nil
-}
+	limit := normalizeLimit(filter.Limit)
 
-func (ms *MissionStore) AssignCat(ctx context.Context, catID int64, missionID int64) error {
 	query := `
-		UPDATE missions
-		SET cat_id = $1
-		WHERE id = $2;
+		WITH page AS (
+			SELECT id, cat_id, status, assigned_at, completed_at, version, deleted_at
+			FROM missions
+			WHERE id > $1 AND ($3 OR deleted_at IS NULL)
+			ORDER BY id
+			LIMIT $2
+		)
+		SELECT p.id, p.cat_id, p.status, p.assigned_at, p.completed_at, p.version, p.deleted_at,
+			t.id, t.mission_id, t.name, t.country, t.is_complete, t.version
+		FROM page p
+		LEFT JOIN targets t ON t.mission_id = p.id
+		ORDER BY p.id, t.id;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := ms.db.ExecContext(
-		ctx,
-		query,
-		catID,
-		missionID,
-	)
+	rows, err := ms.db.QueryContext(ctx, query, afterID, limit+1, filter.IncludeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to get missions: %w", err)
+	}
+	defer rows.Close()
 
+	missions, err := scanMissionsWithTargets(rows)
 	if err != nil {
-		return fmt.Errorf("store: failed to assign cat: %w", err)
+		return nil, err
 	}
 
-	affected, err := res.RowsAffected()
+	page := &Page[Mission]{Data: missions}
+	if len(missions) > limit {
+		page.Data = missions[:limit]
+		page.NextCursor, err = encodeCursor(missionSortID, nil, page.Data[limit-1].ID)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to encode cursor: %w", err)
+		}
+	}
+
+	return page, nil
+}
+
+// scanMissionsWithTargets groups rows of a missions LEFT JOIN targets query,
+// which must be ordered by mission id, into missions with their targets.
+func scanMissionsWithTargets(rows *sql.Rows) ([]Mission, error) {
+	missions := []Mission{}
+	for rows.Next() {
+		var m Mission
+		var (
+			targetID         sql.NullInt64
+			targetMissionID  sql.NullInt64
+			targetName       sql.NullString
+			targetCountry    sql.NullString
+			targetIsComplete sql.NullBool
+			targetVersion    sql.NullInt64
+		)
+
+		err := rows.Scan(
+			&m.ID,
+			&m.CatID,
+			&m.Status,
+			&m.AssignedAt,
+			&m.CompletedAt,
+			&m.Version,
+			&m.DeletedAt,
+			&targetID,
+			&targetMissionID,
+			&targetName,
+			&targetCountry,
+			&targetIsComplete,
+			&targetVersion,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to scan row: %w", err)
+		}
+
+		if len(missions) == 0 || missions[len(missions)-1].ID != m.ID {
+			m.Targets = []Target{}
+			missions = append(missions, m)
+		}
+
+		if targetID.Valid {
+			last := &missions[len(missions)-1]
+			last.Targets = append(last.Targets, Target{
+				ID:         targetID.Int64,
+				MissionID:  targetMissionID.Int64,
+				Name:       targetName.String,
+				Country:    targetCountry.String,
+				IsComplete: targetIsComplete.Bool,
+				Version:    targetVersion.Int64,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to iterate rows: %w", err)
+	}
+
+	return missions, nil
+}
+
+// AssignCat hands a draft mission at version, see AnyVersion, to the cat and
+// moves it to assigned. The cat row is locked before the mission, so two
+// assignments of the same mission never interleave. The busy check below is
+// only a fast path: under READ COMMITTED it can't see a mission another
+// transaction makes active, missions_active_cat_idx is what keeps a cat on
+// one mission and its violation is reported as ErrCatBusy.
+func (ms *MissionStore) AssignCat(ctx context.Context, catID int64, missionID int64, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := ms.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("store: failed to retrieve affected rows: %w", err)
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
 	}
 
-	if affected == 0 {
-		return ErrorNotFound
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	queryLockCat := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM missions
+			WHERE cat_id = c.id AND status IN ('assigned', 'in_progress') AND deleted_at IS NULL
+		)
+		FROM cats c
+		WHERE c.id = $1 AND c.deleted_at IS NULL
+		FOR UPDATE OF c;
+	`
+
+	var busy bool
+	err = tx.QueryRowContext(ctx, queryLockCat, catID).Scan(&busy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorNotFound
+			return err
+		}
+		return fmt.Errorf("store: failed to lock cat: %w", err)
+	}
+
+	if busy {
+		err = ErrCatBusy
+		return err
+	}
+
+	var mission *Mission
+	mission, err = lockMission(ctx, tx, missionID)
+	if err != nil {
+		return err
+	}
+
+	if mission.CatID != nil {
+		err = ErrMissionHasSpy
+		return err
+	}
+
+	query := `
+		UPDATE missions
+		SET cat_id = $1
+		WHERE id = $2;
+	`
+
+	if _, err = tx.ExecContext(ctx, query, catID, missionID); err != nil {
+		if isUniqueViolation(err) {
+			err = ErrCatBusy
+			return err
+		}
+		return fmt.Errorf("store: failed to assign cat: %w", err)
+	}
+
+	before := *mission
+	mission.Status = MissionAssigned
+	mission.Version = version
+	if err = transition(ctx, tx, mission); err != nil {
+		// the mission turns active here, the index rejects a second one
+		if isUniqueViolation(err) {
+			err = ErrCatBusy
+		}
+		return err
+	}
+
+	if err = recordAudit(ctx, tx, AuditAssign, AuditMission, missionID, &before, mission); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
 	}
 
 	return nil
@@ -266,7 +657,7 @@ func (ms *MissionStore) HasAssignedSpy(ctx context.Context, missionID int64) (bo
 	query := `
 		SELECT cat_id
 		FROM missions
-		WHERE id = $1;
+		WHERE id = $1 AND deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -286,87 +677,349 @@ func (ms *MissionStore) HasAssignedSpy(ctx context.Context, missionID int64) (bo
 	return catID != nil, nil
 }
 
-func (ms *MissionStore) AddTarget(ctx context.Context, id int64, target *Target) error {
+// CountByStatus counts the missions in each status, statuses without
+// missions are left out.
+func (ms *MissionStore) CountByStatus(ctx context.Context) (map[MissionStatus]int, error) {
 	query := `
-		INSERT INTO targets (mission_id, name, country, is_complete)
-		VALUES ($1, $2, $3, false)
-		RETURNING id;
+		SELECT status, count(*)
+		FROM missions
+		WHERE deleted_at IS NULL
+		GROUP BY status;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := ms.db.QueryRowContext(
+	rows, err := ms.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to count missions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[MissionStatus]int{}
+	for rows.Next() {
+		var status MissionStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("store: failed to scan mission count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to count missions: %w", err)
+	}
+
+	return counts, nil
+}
+
+// AddTarget adds a target to the mission while holding the mission lock, so
+// parallel requests can't push the mission over MaxMissionTargets. The
+// mission moves to its next version.
+func (ms *MissionStore) AddTarget(ctx context.Context, id int64, target *Target) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var mission *Mission
+	if mission, err = lockMission(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// a finished mission is frozen, completed ones have every target done
+	if mission.Status.IsFinal() {
+		err = ErrMissionFinished
+		return err
+	}
+
+	var count int
+	count, err = countTargets(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if count >= MaxMissionTargets {
+		err = ErrTargetLimit
+		return err
+	}
+
+	query := `
+		INSERT INTO targets (mission_id, name, country, is_complete)
+		VALUES ($1, $2, $3, false)
+		RETURNING id, version;
+	`
+
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		id,
 		target.Name,
 		target.Country,
-	).Scan(&target.ID)
+	).Scan(&target.ID, &target.Version)
 
 	if err != nil {
 		return fmt.Errorf("store: failed to add target: %w", err)
 	}
 
+	target.MissionID = id
+
+	if err = bumpMission(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err = recordAudit(ctx, tx, AuditCreate, AuditTarget, target.ID, nil, target); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (ms *MissionStore) RemoveTarget(ctx context.Context, targetId int64) error {
-	query := `
-		DELETE FROM targets
-		WHERE id = $1;
-	`
-
+// RemoveTarget deletes an incomplete target at version, see AnyVersion,
+// unless it is the last one of its mission. The mission is locked first, so
+// two parallel deletes can't both see a spare target, and moves to its next
+// version.
+func (ms *MissionStore) RemoveTarget(ctx context.Context, targetId int64, version int64) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := ms.db.ExecContext(ctx, query, targetId)
+	tx, err := ms.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("store: failed to remove target: %w", err)
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
 	}
 
-	rows, err := res.RowsAffected()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// the target may have changed while we were waiting for the lock
+	var target *Target
+	if _, target, err = lockTargetWithMission(ctx, tx, targetId); err != nil {
+		return err
+	}
+
+	if target.IsComplete {
+		err = ErrTargetComplete
+		return err
+	}
+
+	var count int
+	count, err = countTargets(ctx, tx, target.MissionID)
 	if err != nil {
+		return err
+	}
+
+	if count <= 1 {
+		err = ErrLastTarget
+		return err
+	}
+
+	query := `
+		DELETE FROM targets
+		WHERE id = $1 AND ($2::BIGINT = 0 OR version = $2);
+	`
+
+	var res sql.Result
+	if res, err = tx.ExecContext(ctx, query, targetId, version); err != nil {
+		return fmt.Errorf("store: failed to remove target: %w", err)
+	}
+
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
 		return fmt.Errorf("store: failed to retrieve affected rows: %w", err)
 	}
 
-	if rows == 0 {
-		return ErrorNotFound
+	// the target is locked, so it can only be at another version
+	if affected == 0 {
+		err = ErrVersionMismatch
+		return err
 	}
 
 	// on delete cascade will do the thing with notes
 
+	if err = bumpMission(ctx, tx, target.MissionID); err != nil {
+		return err
+	}
+
+	if err = recordAudit(ctx, tx, AuditDelete, AuditTarget, targetId, target, nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (ms *MissionStore) UpdateTarget(ctx context.Context, target *Target) error {
+func countTargets(ctx context.Context, tx *sql.Tx, missionID int64) (int, error) {
 	query := `
-	UPDATE targets
-	SET is_complete = $1
-	WHERE id = $2;
+		SELECT COUNT(id)
+		FROM targets
+		WHERE mission_id = $1;
 	`
 
+	var count int
+	if err := tx.QueryRowContext(ctx, query, missionID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("store: failed to count targets: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpdateTarget sets is_complete when target.Version matches, see AnyVersion.
+func (ms *MissionStore) UpdateTarget(ctx context.Context, target *Target) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	res, err := ms.db.ExecContext(
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var mission *Mission
+	var before *Target
+	if mission, before, err = lockTargetWithMission(ctx, tx, target.ID); err != nil {
+		return err
+	}
+
+	// checked under the locks, so a mission finished or unassigned meanwhile
+	// stops the write even with AnyVersion
+	switch {
+	case before.IsComplete:
+		err = ErrTargetComplete
+	case mission.CatID == nil:
+		err = ErrMissionHasNoSpy
+	case mission.Status.IsFinal():
+		err = ErrMissionFinished
+	}
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE targets
+	SET is_complete = $1, version = version + 1
+	WHERE id = $2 AND ($3::BIGINT = 0 OR version = $3)
+	RETURNING version;
+	`
+
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		target.IsComplete,
 		target.ID,
-	)
+		target.Version,
+	).Scan(&target.Version)
 
 	if err != nil {
+		// the row is locked, so it can only be at another version
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVersionMismatch
+		}
 		return fmt.Errorf("store: failed to update target: %w", err)
 	}
 
-	affected, err := res.RowsAffected()
+	after := *before
+	after.IsComplete, after.Version = target.IsComplete, target.Version
+	if err = recordAudit(ctx, tx, AuditUpdate, AuditTarget, target.ID, before, &after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// lockTarget reads the target row and locks it until tx ends, targets of
+// deleted missions are not found.
+func lockTarget(ctx context.Context, tx *sql.Tx, id int64) (*Target, error) {
+	query := `
+		SELECT t.id, t.mission_id, t.name, t.country, t.is_complete, t.version
+		FROM targets t
+		JOIN missions m ON m.id = t.mission_id
+		WHERE t.id = $1 AND m.deleted_at IS NULL
+		FOR UPDATE OF t;
+	`
+
+	var target Target
+	err := tx.QueryRowContext(ctx, query, id).
+		Scan(&target.ID, &target.MissionID, &target.Name, &target.Country, &target.IsComplete, &target.Version)
 	if err != nil {
-		return fmt.Errorf("store: failed to retrieve affected rows: %w", err)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, fmt.Errorf("store: failed to lock target: %w", err)
+		}
 	}
 
-	if affected == 0 {
-		return ErrorNotFound
+	return &target, nil
+}
+
+// lockTargetWithMission locks the mission of the target and then the target,
+// the order every write takes them in.
+func lockTargetWithMission(ctx context.Context, tx *sql.Tx, targetID int64) (*Mission, *Target, error) {
+	query := `
+		SELECT mission_id
+		FROM targets
+		WHERE id = $1;
+	`
+
+	var missionID int64
+	if err := tx.QueryRowContext(ctx, query, targetID).Scan(&missionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrorNotFound
+		}
+		return nil, nil, fmt.Errorf("store: failed to retrieve target: %w", err)
+	}
+
+	mission, err := lockMission(ctx, tx, missionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := lockTarget(ctx, tx, targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mission, target, nil
+}
+
+// bumpMission moves a locked mission to its next version.
+func bumpMission(ctx context.Context, tx *sql.Tx, id int64) error {
+	query := `
+		UPDATE missions
+		SET version = version + 1
+		WHERE id = $1;
+	`
+
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("store: failed to bump mission version: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrInvalidTransition = errors.New("store: invalid mission state transition")
+var ErrIncompleteTargets = errors.New("store: mission has incomplete targets")
+
+type MissionStatus string
+
+const (
+	MissionDraft      MissionStatus = "draft"
+	MissionAssigned   MissionStatus = "assigned"
+	MissionInProgress MissionStatus = "in_progress"
+	MissionCompleted  MissionStatus = "completed"
+	MissionAborted    MissionStatus = "aborted"
+)
+
+// Enum lists every status, API docs use it to describe the field.
+func (MissionStatus) Enum() []any {
+	return []any{MissionDraft, MissionAssigned, MissionInProgress, MissionCompleted, MissionAborted}
+}
+
+// missionTransitions is the whole mission lifecycle, every status change in
+// the store goes through it.
+var missionTransitions = map[MissionStatus][]MissionStatus{
+	MissionDraft:      {MissionAssigned, MissionAborted},
+	MissionAssigned:   {MissionInProgress, MissionAborted},
+	MissionInProgress: {MissionCompleted, MissionAborted},
+}
+
+func (s MissionStatus) CanTransitionTo(next MissionStatus) bool {
+	return slices.Contains(missionTransitions[s], next)
+}
+
+// IsFinal reports whether the mission is over, final missions are frozen.
+func (s MissionStatus) IsFinal() bool {
+	return s == MissionCompleted || s == MissionAborted
+}
+
+// IsActive reports whether a spy is currently busy with the mission.
+func (s MissionStatus) IsActive() bool {
+	return s == MissionAssigned || s == MissionInProgress
+}
+
+// lockMission reads the mission row and locks it until tx ends, so
+// concurrent changes of the same mission are serialized. Deleted missions
+// are not found.
+func lockMission(ctx context.Context, tx *sql.Tx, id int64) (*Mission, error) {
+	query := `
+		SELECT id, cat_id, status, assigned_at, completed_at, version
+		FROM missions
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE;
+	`
+
+	var mission Mission
+	err := tx.QueryRowContext(ctx, query, id).
+		Scan(&mission.ID, &mission.CatID, &mission.Status, &mission.AssignedAt, &mission.CompletedAt, &mission.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, fmt.Errorf("store: failed to lock mission: %w", err)
+		}
+	}
+
+	return &mission, nil
+}
+
+// transition moves a locked mission to mission.Status and fills in the
+// resulting row. mission.Version must match unless it is AnyVersion.
+// Completing a mission requires all its targets to be complete.
+func transition(ctx context.Context, tx *sql.Tx, mission *Mission) error {
+	locked, err := lockMission(ctx, tx, mission.ID)
+	if err != nil {
+		return err
+	}
+
+	if !locked.Status.CanTransitionTo(mission.Status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, locked.Status, mission.Status)
+	}
+
+	if mission.Status == MissionCompleted {
+		queryIncomplete := `
+			SELECT EXISTS (
+				SELECT 1
+				FROM targets
+				WHERE mission_id = $1 AND is_complete = FALSE
+			);
+		`
+
+		var incomplete bool
+		if err := tx.QueryRowContext(ctx, queryIncomplete, mission.ID).Scan(&incomplete); err != nil {
+			return fmt.Errorf("store: failed to check mission targets: %w", err)
+		}
+
+		if incomplete {
+			return ErrIncompleteTargets
+		}
+	}
+
+	query := `
+		UPDATE missions
+		SET status = $1,
+			assigned_at = CASE WHEN $1 = 'assigned' THEN now() ELSE assigned_at END,
+			completed_at = CASE WHEN $1 IN ('completed', 'aborted') THEN now() ELSE completed_at END,
+			version = version + 1
+		WHERE id = $2 AND ($3::BIGINT = 0 OR version = $3)
+		RETURNING cat_id, assigned_at, completed_at, version;
+	`
+
+	err = tx.QueryRowContext(ctx, query, mission.Status, mission.ID, mission.Version).
+		Scan(&mission.CatID, &mission.AssignedAt, &mission.CompletedAt, &mission.Version)
+	if err != nil {
+		// the row is locked, so it can only be at another version
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVersionMismatch
+		}
+		return fmt.Errorf("store: failed to update mission status: %w", err)
+	}
+
+	return nil
+}
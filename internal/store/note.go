@@ -2,38 +2,328 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 )
 
+// ErrNotesFrozen is a note write on a completed target or a finished mission.
+var ErrNotesFrozen = errors.New("store: notes are frozen")
+
 type Note struct {
 	ID       int64  `json:"id"`
 	TargetID int64  `json:"target_id"`
-	Note     string `json:"note"`
+	Note     string `json:"note" validate:"notblank,max=2000"`
 	// i know that it wasn't in task, but it's just makes sense
 	CreatedAt time.Time `json:"created_at"`
 }
 
-func (ms *MissionStore) AddNote(ctx context.Context, note *Note) error {
+func (ms *MissionStore) AddNote(ctx context.Context, note *Note) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = lockOpenTarget(ctx, tx, note.TargetID); err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO notes (target_id, note)
 		VALUES ($1, $2)
-		RETURNING id;
+		RETURNING id, created_at;
 	`
 
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		note.TargetID,
+		note.Note,
+	).Scan(&note.ID, &note.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("store: failed to create note: %w", err)
+	}
+
+	if err = recordAudit(ctx, tx, AuditCreate, AuditNote, note.ID, nil, note); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (ms *MissionStore) GetNoteByID(ctx context.Context, id int64) (*Note, error) {
+	query := `
+	SELECT n.id, n.target_id, n.note, n.created_at
+	FROM notes n
+	JOIN targets t ON t.id = n.target_id
+	JOIN missions m ON m.id = t.mission_id
+	WHERE n.id = $1 AND m.deleted_at IS NULL;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var note Note
+	err := ms.db.QueryRowContext(ctx, query, id).
+		Scan(
+			&note.ID,
+			&note.TargetID,
+			&note.Note,
+			&note.CreatedAt,
+		)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, fmt.Errorf("store: failed to retrieve note: %w", err)
+		}
+	}
+
+	return &note, nil
+}
+
+func (ms *MissionStore) GetAllTargetNotes(ctx context.Context, targetID int64) ([]Note, error) {
+	query := `
+		SELECT n.id, n.target_id, n.note, n.created_at
+		FROM notes n
+		JOIN targets t ON t.id = n.target_id
+		JOIN missions m ON m.id = t.mission_id
+		WHERE n.target_id = $1 AND m.deleted_at IS NULL
+		ORDER BY n.created_at, n.id;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := ms.db.QueryContext(ctx, query, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []Note{}
+	for rows.Next() {
+		var n Note
+		err = rows.Scan(&n.ID, &n.TargetID, &n.Note, &n.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to scan row: %w", err)
+		}
+
+		notes = append(notes, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to iterate rows: %w", err)
+	}
+
+	return notes, nil
+}
+
+// GetByIDWithNotes returns the mission with its targets and every target's
+// notes. Notes for all targets are fetched with one query.
+func (ms *MissionStore) GetByIDWithNotes(ctx context.Context, id int64) (*Mission, error) {
+	mission, err := ms.GetByIDWithTargets(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT n.id, n.target_id, n.note, n.created_at
+		FROM notes n
+		JOIN targets t ON t.id = n.target_id
+		WHERE t.mission_id = $1
+		ORDER BY n.created_at, n.id;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := ms.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to get notes for mission %d: %w", id, err)
+	}
+	defer rows.Close()
+
+	byTarget := make(map[int64][]Note, len(mission.Targets))
+	for rows.Next() {
+		var n Note
+		err = rows.Scan(&n.ID, &n.TargetID, &n.Note, &n.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("store: failed to scan row: %w", err)
+		}
+
+		byTarget[n.TargetID] = append(byTarget[n.TargetID], n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to iterate rows: %w", err)
+	}
+
+	for i, t := range mission.Targets {
+		notes := byTarget[t.ID]
+		if notes == nil {
+			notes = []Note{}
+		}
+		mission.Targets[i].Notes = notes
+	}
+
+	return mission, nil
+}
+
+func (ms *MissionStore) UpdateNote(ctx context.Context, note *Note) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := ms.db.QueryRowContext(
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var before *Note
+	if before, err = lockNote(ctx, tx, note.ID); err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE notes
+	SET note = $1
+	WHERE id = $2
+	RETURNING target_id, created_at;
+	`
+
+	err = tx.QueryRowContext(
 		ctx,
 		query,
-		note.TargetID,
 		note.Note,
-	).Scan(&note.ID)
+		note.ID,
+	).Scan(&note.TargetID, &note.CreatedAt)
 
 	if err != nil {
-		return fmt.Errorf("store: failed to create note: %w", err)
+		return fmt.Errorf("store: failed to update note: %w", err)
+	}
+
+	if err = recordAudit(ctx, tx, AuditUpdate, AuditNote, note.ID, before, note); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
+
+func (ms *MissionStore) RemoveNote(ctx context.Context, noteID int64) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	tx, err := ms.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var note *Note
+	if note, err = lockNote(ctx, tx, noteID); err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM notes
+		WHERE id = $1;
+	`
+
+	if _, err = tx.ExecContext(ctx, query, noteID); err != nil {
+		return fmt.Errorf("store: failed to remove note: %w", err)
+	}
+
+	if err = recordAudit(ctx, tx, AuditDelete, AuditNote, note.ID, note, nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("store: failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// lockOpenTarget locks the target with its mission and makes sure notes may
+// still change, ErrNotesFrozen once either is done.
+func lockOpenTarget(ctx context.Context, tx *sql.Tx, targetID int64) error {
+	mission, target, err := lockTargetWithMission(ctx, tx, targetID)
+	if err != nil {
+		return err
+	}
+
+	if target.IsComplete || mission.Status.IsFinal() {
+		return ErrNotesFrozen
+	}
+
+	return nil
+}
+
+// lockNote locks the open target of the note and then the note, notes of
+// deleted missions are not found.
+func lockNote(ctx context.Context, tx *sql.Tx, id int64) (*Note, error) {
+	queryTarget := `
+		SELECT target_id
+		FROM notes
+		WHERE id = $1;
+	`
+
+	var targetID int64
+	if err := tx.QueryRowContext(ctx, queryTarget, id).Scan(&targetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotFound
+		}
+		return nil, fmt.Errorf("store: failed to retrieve note: %w", err)
+	}
+
+	if err := lockOpenTarget(ctx, tx, targetID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, target_id, note, created_at
+		FROM notes
+		WHERE id = $1
+		FOR UPDATE;
+	`
+
+	var note Note
+	err := tx.QueryRowContext(ctx, query, id).Scan(&note.ID, &note.TargetID, &note.Note, &note.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotFound
+		}
+		return nil, fmt.Errorf("store: failed to lock note: %w", err)
+	}
+
+	return &note, nil
+}
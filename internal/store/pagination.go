@@ -0,0 +1,77 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("store: invalid cursor")
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the keyset position of the last row on a page. It is handed to
+// clients as an opaque base64 string, so its layout can change freely.
+type cursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v,omitempty"`
+	ID    int64           `json:"id"`
+}
+
+func encodeCursor(sort string, value any, id int64) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	buf, err := json.Marshal(cursor{Sort: sort, Value: raw, ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// decodeCursor parses token and stores the sort value into value. The cursor
+// must have been issued for the same sort, otherwise it points nowhere.
+func decodeCursor(token string, sort string, value any) (int64, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(buf, &c); err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	if c.Sort != sort {
+		return 0, ErrInvalidCursor
+	}
+
+	if value != nil {
+		if err := json.Unmarshal(c.Value, value); err != nil {
+			return 0, ErrInvalidCursor
+		}
+	}
+
+	return c.ID, nil
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return limit
+}
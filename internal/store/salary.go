@@ -0,0 +1,254 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SalaryChange is one entry of a cat's salary history, the salary holds
+// from EffectiveFrom until the next entry.
+type SalaryChange struct {
+	ID            int64     `json:"id"`
+	CatID         int64     `json:"cat_id"`
+	Salary        float64   `json:"salary"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	ChangedBy     string    `json:"changed_by"`
+}
+
+// PayrollLine is what one month costs for the cats of a breed and
+// experience bracket.
+type PayrollLine struct {
+	// Month is formatted as PayrollMonthLayout.
+	Month      string  `json:"month"`
+	Breed      string  `json:"breed"`
+	Experience string  `json:"experience"`
+	Cats       int     `json:"cats"`
+	Total      float64 `json:"total"`
+}
+
+const PayrollMonthLayout = "2006-01"
+
+// MaxPayrollMonths bounds the period of a payroll report.
+const MaxPayrollMonths = 120
+
+type experienceBracket struct {
+	name     string
+	maxYears int
+}
+
+// experienceBrackets are ordered, the last one takes everyone above.
+var experienceBrackets = []experienceBracket{
+	{"0-2", 2},
+	{"3-5", 5},
+	{"6-10", 10},
+	{"11+", math.MaxInt},
+}
+
+// ExperienceBracket names the payroll bracket of a cat with years of
+// experience.
+func ExperienceBracket(years int) string {
+	for _, b := range experienceBrackets {
+		if years <= b.maxYears {
+			return b.name
+		}
+	}
+	return experienceBrackets[len(experienceBrackets)-1].name
+}
+
+// payrollMonths lists the first instant of every month from from to to,
+// both included.
+func payrollMonths(from, to time.Time) []time.Time {
+	var months []time.Time
+	for month := monthStart(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		months = append(months, month)
+	}
+	return months
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// payrollRow is the pay of the cats sharing a month, breed and experience.
+type payrollRow struct {
+	month      time.Time
+	breed      string
+	experience int
+	cats       int
+	total      float64
+}
+
+// groupPayroll folds rows into experience brackets and orders the lines by
+// month, breed and bracket.
+func groupPayroll(rows []payrollRow) []PayrollLine {
+	type key struct {
+		month   time.Time
+		breed   string
+		bracket string
+	}
+
+	byKey := map[key]*PayrollLine{}
+	for _, r := range rows {
+		k := key{r.month, r.breed, ExperienceBracket(r.experience)}
+		line, ok := byKey[k]
+		if !ok {
+			line = &PayrollLine{Month: r.month.Format(PayrollMonthLayout), Breed: r.breed, Experience: k.bracket}
+			byKey[k] = line
+		}
+		line.Cats += r.cats
+		line.Total += r.total
+	}
+
+	bracketIndex := func(name string) int {
+		return slices.IndexFunc(experienceBrackets, func(b experienceBracket) bool { return b.name == name })
+	}
+
+	lines := make([]PayrollLine, 0, len(byKey))
+	for _, line := range byKey {
+		line.Total = roundSalary(line.Total)
+		lines = append(lines, *line)
+	}
+	slices.SortFunc(lines, func(a, b PayrollLine) int {
+		return cmp.Or(
+			strings.Compare(a.Month, b.Month),
+			strings.Compare(a.Breed, b.Breed),
+			cmp.Compare(bracketIndex(a.Experience), bracketIndex(b.Experience)),
+		)
+	})
+
+	return lines
+}
+
+func recordSalary(ctx context.Context, tx *sql.Tx, catID int64, salary float64, changedBy string) error {
+	query := `
+		INSERT INTO salary_history (cat_id, salary, changed_by)
+		VALUES ($1, $2, $3);
+	`
+
+	if _, err := tx.ExecContext(ctx, query, catID, salary, changedBy); err != nil {
+		return fmt.Errorf("store: failed to record salary change: %w", err)
+	}
+
+	return nil
+}
+
+// SalaryHistory returns every salary the cat had, oldest first.
+func (cs *CatStore) SalaryHistory(ctx context.Context, catID int64) ([]SalaryChange, error) {
+	query := `
+		SELECT h.id, h.cat_id, h.salary, h.effective_from, h.changed_by
+		FROM cats c
+		LEFT JOIN salary_history h ON h.cat_id = c.id
+		WHERE c.id = $1 AND c.deleted_at IS NULL
+		ORDER BY h.effective_from, h.id;
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := cs.db.QueryContext(ctx, query, catID)
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to get salary history: %w", err)
+	}
+	defer rows.Close()
+
+	found := false
+	history := []SalaryChange{}
+	for rows.Next() {
+		found = true
+
+		var id, cat sql.NullInt64
+		var salary sql.NullFloat64
+		var effectiveFrom sql.NullTime
+		var changedBy sql.NullString
+		if err := rows.Scan(&id, &cat, &salary, &effectiveFrom, &changedBy); err != nil {
+			return nil, fmt.Errorf("store: failed to scan salary change: %w", err)
+		}
+
+		// the cat exists but has no history
+		if !id.Valid {
+			continue
+		}
+
+		history = append(history, SalaryChange{
+			ID:            id.Int64,
+			CatID:         cat.Int64,
+			Salary:        salary.Float64,
+			EffectiveFrom: effectiveFrom.Time,
+			ChangedBy:     changedBy.String,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to get salary history: %w", err)
+	}
+
+	if !found {
+		return nil, ErrorNotFound
+	}
+
+	return history, nil
+}
+
+// Payroll reports the pay of every month from from to to, both included.
+// A cat earns in a month the salary in effect at its end, cats created
+// later or deleted before its end are left out. Experience is the cat's
+// current one.
+func (cs *CatStore) Payroll(ctx context.Context, from, to time.Time) ([]PayrollLine, error) {
+	months := payrollMonths(from, to)
+	if len(months) == 0 {
+		return []PayrollLine{}, nil
+	}
+
+	query := `
+		SELECT m.month, c.breed, c.years_of_experience, count(*), sum(h.salary)
+		FROM unnest($1::timestamp[]) AS m(month)
+		CROSS JOIN cats c
+		JOIN LATERAL (
+			SELECT salary
+			FROM salary_history
+			WHERE cat_id = c.id AND effective_from < m.month + interval '1 month'
+			ORDER BY effective_from DESC, id DESC
+			LIMIT 1
+		) h ON true
+		WHERE c.deleted_at IS NULL OR c.deleted_at >= m.month + interval '1 month'
+		GROUP BY m.month, c.breed, c.years_of_experience;
+	`
+
+	starts := make([]string, len(months))
+	for i, month := range months {
+		starts[i] = month.Format(time.DateOnly)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := cs.db.QueryContext(ctx, query, pq.Array(starts))
+	if err != nil {
+		return nil, fmt.Errorf("store: failed to compute payroll: %w", err)
+	}
+	defer rows.Close()
+
+	var payroll []payrollRow
+	for rows.Next() {
+		var r payrollRow
+		if err := rows.Scan(&r.month, &r.breed, &r.experience, &r.cats, &r.total); err != nil {
+			return nil, fmt.Errorf("store: failed to scan payroll: %w", err)
+		}
+		r.month = monthStart(r.month)
+		payroll = append(payroll, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: failed to compute payroll: %w", err)
+	}
+
+	return groupPayroll(payroll), nil
+}
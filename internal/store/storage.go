@@ -5,44 +5,94 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 var ErrorNotFound = errors.New("store: resource not found")
 var QueryTimeoutDuration = 5 * time.Second
 var ErrConflict = errors.New("store: resource already exists")
+var ErrVersionMismatch = errors.New("store: resource version has changed")
+var ErrNotDeleted = errors.New("store: resource is not deleted")
+
+// AnyVersion skips the version check of a write. Every other version must
+// match the row, which moves to the next version on each write.
+const AnyVersion int64 = 0
 
 type CRUD[T any] interface {
 	Create(context.Context, *T) error
 	Update(context.Context, *T) error
-	Delete(context.Context, int64) error
+	// Delete removes the row with the id at the version, see AnyVersion.
+	Delete(ctx context.Context, id int64, version int64) error
 	GetByID(context.Context, int64) (*T, error)
 	GetAll(context.Context) ([]T, error)
 }
 
+// SoftDelete is implemented by tables whose Delete only marks rows as
+// deleted. Reads skip deleted rows unless asked otherwise.
+type SoftDelete[T any] interface {
+	// Restore brings back a deleted row, ErrNotDeleted when it isn't.
+	Restore(ctx context.Context, id int64) (*T, error)
+	// Purge removes the rows deleted before the time for good and returns
+	// how many there were.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
 type Storage struct {
 	Cat interface {
 		CRUD[Cat]
+		SoftDelete[Cat]
+		List(context.Context, CatFilter) (*Page[Cat], error)
 		HasIncompleteMission(context.Context, int64) (bool, error)
+		Retire(ctx context.Context, id int64, version int64, successorID int64) error
+		CountIdle(context.Context) (int, error)
+		SalaryHistory(context.Context, int64) ([]SalaryChange, error)
+		Payroll(ctx context.Context, from, to time.Time) ([]PayrollLine, error)
 	}
 	Mission interface {
 		CRUD[Mission]
-		AssignCat(context.Context, int64, int64) error
+		SoftDelete[Mission]
+		AssignCat(ctx context.Context, catID int64, missionID int64, version int64) error
 		AddTarget(context.Context, int64, *Target) error
-		RemoveTarget(context.Context, int64) error
+		RemoveTarget(ctx context.Context, targetID int64, version int64) error
 		AddNote(context.Context, *Note) error
+		GetNoteByID(context.Context, int64) (*Note, error)
+		GetAllTargetNotes(context.Context, int64) ([]Note, error)
+		UpdateNote(context.Context, *Note) error
+		RemoveNote(context.Context, int64) error
+		GetByIDWithNotes(context.Context, int64) (*Mission, error)
 		GetAllWithTargets(context.Context) ([]Mission, error)
+		ListWithTargets(context.Context, MissionFilter) (*Page[Mission], error)
 		GetByIDWithTargets(context.Context, int64) (*Mission, error)
 		GetAllMissionTargets(context.Context, int64) ([]Target, error)
 		HasAssignedSpy(context.Context, int64) (bool, error)
 		GetTargetsQuantity(context.Context, int64) (int, error)
 		GetTargetByID(context.Context, int64) (*Target, error)
 		UpdateTarget(context.Context, *Target) error
+		CountByStatus(context.Context) (map[MissionStatus]int, error)
 	}
+	Idempotency interface {
+		Claim(ctx context.Context, req *IdempotentRequest, ttl time.Duration) (*IdempotentRequest, error)
+		Complete(context.Context, *IdempotentRequest) error
+		Release(context.Context, *IdempotentRequest) error
+		DeleteExpired(context.Context) (int64, error)
+	}
+	Audit interface {
+		List(context.Context, AuditFilter) (*Page[AuditEntry], error)
+	}
+}
+
+// isUniqueViolation reports whether err comes from a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Cat:     &CatStore{db},
-		Mission: &MissionStore{db},
+		Cat:         &CatStore{db},
+		Mission:     &MissionStore{db},
+		Idempotency: &IdempotencyStore{db},
+		Audit:       &AuditStore{db},
 	}
 }
@@ -0,0 +1,1089 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"spy-cat-agency/internal/db"
+	"spy-cat-agency/internal/store"
+	"testing"
+	"time"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, store.NewMemoryStorage)
+}
+
+// TestPostgresStorage needs a migrated Postgres, point TEST_DB_ADDR at it.
+// The suite only relies on rows it creates, so the database may hold data.
+func TestPostgresStorage(t *testing.T) {
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR is not set")
+	}
+
+	conn, err := db.New(addr, 10, 10, "1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	testStorage(t, func() store.Storage { return store.NewStorage(conn) })
+}
+
+// testStorage is the conformance suite every Storage implementation must pass.
+func testStorage(t *testing.T, newStorage func() store.Storage) {
+	tests := []struct {
+		name string
+		run  func(*testing.T, store.Storage)
+	}{
+		{"CatCRUD", testCatCRUD},
+		{"CatList", testCatList},
+		{"MissionCreate", testMissionCreate},
+		{"MissionLifecycle", testMissionLifecycle},
+		{"MissionList", testMissionList},
+		{"Targets", testTargets},
+		{"Notes", testNotes},
+		{"DeleteMissionCascades", testDeleteMissionCascades},
+		{"RetireCat", testRetireCat},
+		{"RetireToInvalidSuccessor", testRetireToInvalidSuccessor},
+		{"Counts", testCounts},
+		{"SalaryHistory", testSalaryHistory},
+		{"Payroll", testPayroll},
+		{"Versions", testVersions},
+		{"Idempotency", testIdempotency},
+		{"Audit", testAudit},
+		{"SoftDelete", testSoftDelete},
+		{"DeleteMissionWithSpy", testDeleteMissionWithSpy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStorage())
+		})
+	}
+}
+
+func wantErr(t *testing.T, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// uniqueBreed keeps rows of one test apart from everything else in a shared database.
+func uniqueBreed(t *testing.T) string {
+	return fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+}
+
+func newCat(t *testing.T, s store.Storage, cat store.Cat) *store.Cat {
+	t.Helper()
+	if cat.Name == "" {
+		cat.Name = "Tom"
+	}
+	if cat.Breed == "" {
+		cat.Breed = "Siamese"
+	}
+	must(t, s.Cat.Create(context.Background(), &cat))
+	return &cat
+}
+
+func newMission(t *testing.T, s store.Storage, targets int) *store.Mission {
+	t.Helper()
+	mission := store.Mission{}
+	for i := range targets {
+		mission.Targets = append(mission.Targets, store.Target{
+			Name:    fmt.Sprintf("target %d", i),
+			Country: "Ukraine",
+		})
+	}
+	must(t, s.Mission.Create(context.Background(), &mission))
+	return &mission
+}
+
+func testCatCRUD(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	cat := newCat(t, s, store.Cat{Name: "Garfield", YearsOfExperience: 3, Salary: 100})
+	if cat.ID == 0 {
+		t.Fatal("cat ID was not set")
+	}
+
+	got, err := s.Cat.GetByID(ctx, cat.ID)
+	must(t, err)
+	if *got != *cat {
+		t.Fatalf("got %+v, want %+v", got, cat)
+	}
+
+	must(t, s.Cat.Update(ctx, &store.Cat{ID: cat.ID, Name: "ignored", Salary: 250.456}))
+	got, err = s.Cat.GetByID(ctx, cat.ID)
+	must(t, err)
+	if got.Salary != 250.46 || got.Name != "Garfield" {
+		t.Fatalf("only the salary should change, rounded to cents, got %+v", got)
+	}
+
+	all, err := s.Cat.GetAll(ctx)
+	must(t, err)
+	found := false
+	for _, c := range all {
+		found = found || c.ID == cat.ID
+	}
+	if !found {
+		t.Fatal("GetAll misses the created cat")
+	}
+
+	must(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion))
+
+	_, err = s.Cat.GetByID(ctx, cat.ID)
+	wantErr(t, err, store.ErrorNotFound)
+	wantErr(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion), store.ErrorNotFound)
+	wantErr(t, s.Cat.Update(ctx, cat), store.ErrorNotFound)
+}
+
+func testCatList(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+	breed := uniqueBreed(t)
+
+	var ids []int64
+	for i, salary := range []float64{300, 100, 500, 200, 100} {
+		cat := newCat(t, s, store.Cat{
+			Name:              fmt.Sprintf("cat%d", i),
+			Breed:             breed,
+			YearsOfExperience: i,
+			Salary:            salary,
+		})
+		ids = append(ids, cat.ID)
+	}
+
+	// salary desc, ties by id desc
+	want := []int64{ids[2], ids[0], ids[3], ids[4], ids[1]}
+
+	var got []int64
+	filter := store.CatFilter{Breed: breed, Sort: store.CatSortSalary, Desc: true, Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("pagination does not end")
+		}
+
+		page, err := s.Cat.List(ctx, filter)
+		must(t, err)
+		for _, c := range page.Data {
+			got = append(got, c.ID)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got order %v, want %v", got, want)
+	}
+
+	minExperience, maxSalary := 2, 300.0
+	page, err := s.Cat.List(ctx, store.CatFilter{Breed: breed, MinExperience: &minExperience, MaxSalary: &maxSalary})
+	must(t, err)
+	if len(page.Data) != 2 || page.Data[0].ID != ids[3] || page.Data[1].ID != ids[4] {
+		t.Fatalf("filtered list got %+v", page.Data)
+	}
+
+	_, err = s.Cat.List(ctx, store.CatFilter{Breed: breed, Cursor: "not a cursor"})
+	wantErr(t, err, store.ErrInvalidCursor)
+
+	// a cursor only works with the sort it was issued for
+	_, err = s.Cat.List(ctx, store.CatFilter{Breed: breed, Sort: store.CatSortName, Cursor: filter.Cursor})
+	wantErr(t, err, store.ErrInvalidCursor)
+
+	if _, err = s.Cat.List(ctx, store.CatFilter{Sort: "breed; DROP TABLE cats"}); err == nil {
+		t.Fatal("unknown sort field was accepted")
+	}
+}
+
+func testMissionCreate(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	mission := newMission(t, s, 2)
+	if mission.ID == 0 || mission.Status != store.MissionDraft {
+		t.Fatalf("got %+v, want a draft mission with an ID", mission)
+	}
+	for _, target := range mission.Targets {
+		if target.ID == 0 || target.MissionID != mission.ID {
+			t.Fatalf("target was not created: %+v", target)
+		}
+	}
+
+	got, err := s.Mission.GetByIDWithTargets(ctx, mission.ID)
+	must(t, err)
+	if got.CatID != nil || got.AssignedAt != nil || got.CompletedAt != nil {
+		t.Fatalf("new mission is not blank: %+v", got)
+	}
+	if len(got.Targets) != 2 || got.Targets[0].ID != mission.Targets[0].ID || got.Targets[1].ID != mission.Targets[1].ID {
+		t.Fatalf("got targets %+v, want %+v", got.Targets, mission.Targets)
+	}
+
+	quantity, err := s.Mission.GetTargetsQuantity(ctx, mission.ID)
+	must(t, err)
+	if quantity != 2 {
+		t.Fatalf("got %d targets, want 2", quantity)
+	}
+
+	hasSpy, err := s.Mission.HasAssignedSpy(ctx, mission.ID)
+	must(t, err)
+	if hasSpy {
+		t.Fatal("new mission has a spy")
+	}
+
+	missing := mission.ID + 1_000_000
+	_, err = s.Mission.GetByID(ctx, missing)
+	wantErr(t, err, store.ErrorNotFound)
+	_, err = s.Mission.GetByIDWithTargets(ctx, missing)
+	wantErr(t, err, store.ErrorNotFound)
+	_, err = s.Mission.HasAssignedSpy(ctx, missing)
+	wantErr(t, err, store.ErrorNotFound)
+}
+
+func testMissionLifecycle(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	cat := newCat(t, s, store.Cat{})
+	other := newCat(t, s, store.Cat{})
+	mission := newMission(t, s, 2)
+
+	wantErr(t, s.Mission.AssignCat(ctx, cat.ID+1_000_000, mission.ID, store.AnyVersion), store.ErrorNotFound)
+	wantErr(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID+1_000_000, store.AnyVersion), store.ErrorNotFound)
+
+	must(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, store.AnyVersion))
+
+	got, err := s.Mission.GetByID(ctx, mission.ID)
+	must(t, err)
+	if got.Status != store.MissionAssigned || got.CatID == nil || *got.CatID != cat.ID || got.AssignedAt == nil {
+		t.Fatalf("mission was not assigned: %+v", got)
+	}
+
+	busy, err := s.Cat.HasIncompleteMission(ctx, cat.ID)
+	must(t, err)
+	if !busy {
+		t.Fatal("assigned cat is not busy")
+	}
+
+	wantErr(t, s.Mission.AssignCat(ctx, other.ID, mission.ID, store.AnyVersion), store.ErrMissionHasSpy)
+	wantErr(t, s.Mission.AssignCat(ctx, cat.ID, newMission(t, s, 1).ID, store.AnyVersion), store.ErrCatBusy)
+
+	move := func(status store.MissionStatus) error {
+		return s.Mission.Update(ctx, &store.Mission{ID: mission.ID, Status: status})
+	}
+
+	wantErr(t, move(store.MissionCompleted), store.ErrInvalidTransition)
+	must(t, move(store.MissionInProgress))
+	wantErr(t, move(store.MissionCompleted), store.ErrIncompleteTargets)
+
+	for _, target := range mission.Targets {
+		target.IsComplete = true
+		must(t, s.Mission.UpdateTarget(ctx, &target))
+	}
+
+	completed := &store.Mission{ID: mission.ID, Status: store.MissionCompleted}
+	must(t, s.Mission.Update(ctx, completed))
+	if completed.CompletedAt == nil || completed.AssignedAt == nil || completed.CatID == nil {
+		t.Fatalf("update did not return the row: %+v", completed)
+	}
+
+	busy, err = s.Cat.HasIncompleteMission(ctx, cat.ID)
+	must(t, err)
+	if busy {
+		t.Fatal("cat is busy after its mission completed")
+	}
+
+	wantErr(t, move(store.MissionAborted), store.ErrInvalidTransition)
+	wantErr(t, s.Mission.Update(ctx, &store.Mission{ID: mission.ID + 1_000_000, Status: store.MissionAborted}), store.ErrorNotFound)
+
+	aborted := newMission(t, s, 1)
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: aborted.ID, Status: store.MissionAborted}))
+	wantErr(t, s.Mission.AssignCat(ctx, other.ID, aborted.ID, store.AnyVersion), store.ErrInvalidTransition)
+
+	got, err = s.Mission.GetByID(ctx, aborted.ID)
+	must(t, err)
+	if got.CatID != nil {
+		t.Fatal("failed assignment left the cat on the mission")
+	}
+	if got.CompletedAt == nil || got.AssignedAt != nil {
+		t.Fatalf("got assigned at %v and ended at %v, want only the end of the aborted mission", got.AssignedAt, got.CompletedAt)
+	}
+}
+
+func testMissionList(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	created := map[int64]int{}
+	for i := 1; i <= 3; i++ {
+		created[newMission(t, s, i).ID] = i
+	}
+
+	seen := 0
+	var lastID int64
+	filter := store.MissionFilter{Limit: 2}
+	for {
+		page, err := s.Mission.ListWithTargets(ctx, filter)
+		must(t, err)
+
+		for _, m := range page.Data {
+			if m.ID <= lastID {
+				t.Fatalf("missions are out of order, %d after %d", m.ID, lastID)
+			}
+			lastID = m.ID
+
+			if want, ok := created[m.ID]; ok {
+				seen++
+				if len(m.Targets) != want {
+					t.Fatalf("mission %d has %d targets, want %d", m.ID, len(m.Targets), want)
+				}
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	if seen != len(created) {
+		t.Fatalf("listed %d of %d created missions", seen, len(created))
+	}
+
+	_, err := s.Mission.ListWithTargets(ctx, store.MissionFilter{Cursor: "not a cursor"})
+	wantErr(t, err, store.ErrInvalidCursor)
+}
+
+func testTargets(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	mission := newMission(t, s, 1)
+
+	for range store.MaxMissionTargets - 1 {
+		target := store.Target{Name: "extra", Country: "Poland"}
+		must(t, s.Mission.AddTarget(ctx, mission.ID, &target))
+		if target.ID == 0 || target.MissionID != mission.ID {
+			t.Fatalf("target was not added: %+v", target)
+		}
+	}
+
+	wantErr(t, s.Mission.AddTarget(ctx, mission.ID, &store.Target{Name: "one too many", Country: "Poland"}), store.ErrTargetLimit)
+	wantErr(t, s.Mission.AddTarget(ctx, mission.ID+1_000_000, &store.Target{Name: "lost", Country: "Poland"}), store.ErrorNotFound)
+
+	targets, err := s.Mission.GetAllMissionTargets(ctx, mission.ID)
+	must(t, err)
+	if len(targets) != store.MaxMissionTargets {
+		t.Fatalf("got %d targets, want %d", len(targets), store.MaxMissionTargets)
+	}
+
+	done := targets[0]
+	done.IsComplete = true
+	wantErr(t, s.Mission.UpdateTarget(ctx, &done), store.ErrMissionHasNoSpy)
+	must(t, s.Mission.AssignCat(ctx, newCat(t, s, store.Cat{}).ID, mission.ID, store.AnyVersion))
+	must(t, s.Mission.UpdateTarget(ctx, &done))
+	wantErr(t, s.Mission.UpdateTarget(ctx, &store.Target{ID: done.ID, Version: store.AnyVersion}), store.ErrTargetComplete)
+
+	got, err := s.Mission.GetTargetByID(ctx, done.ID)
+	must(t, err)
+	if !got.IsComplete {
+		t.Fatal("target was not completed")
+	}
+
+	wantErr(t, s.Mission.RemoveTarget(ctx, done.ID, store.AnyVersion), store.ErrTargetComplete)
+	must(t, s.Mission.RemoveTarget(ctx, targets[1].ID, store.AnyVersion))
+	wantErr(t, s.Mission.RemoveTarget(ctx, targets[1].ID, store.AnyVersion), store.ErrorNotFound)
+
+	// only the completed target and this one are left
+	must(t, s.Mission.RemoveTarget(ctx, targets[2].ID, store.AnyVersion))
+	last := newMission(t, s, 1)
+	wantErr(t, s.Mission.RemoveTarget(ctx, last.Targets[0].ID, store.AnyVersion), store.ErrLastTarget)
+
+	_, err = s.Mission.GetTargetByID(ctx, targets[2].ID)
+	wantErr(t, err, store.ErrorNotFound)
+	wantErr(t, s.Mission.UpdateTarget(ctx, &targets[2]), store.ErrorNotFound)
+
+	// AnyVersion doesn't get past a finished mission
+	aborted := newMission(t, s, 1)
+	must(t, s.Mission.AssignCat(ctx, newCat(t, s, store.Cat{}).ID, aborted.ID, store.AnyVersion))
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: aborted.ID, Status: store.MissionAborted}))
+	wantErr(t, s.Mission.UpdateTarget(ctx, &store.Target{ID: aborted.Targets[0].ID, IsComplete: true}), store.ErrMissionFinished)
+	wantErr(t, s.Mission.AddTarget(ctx, aborted.ID, &store.Target{Name: "late", Country: "Poland"}), store.ErrMissionFinished)
+
+	// a completed mission gains no open targets
+	completed := newMission(t, s, 1)
+	must(t, s.Mission.AssignCat(ctx, newCat(t, s, store.Cat{}).ID, completed.ID, store.AnyVersion))
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: completed.ID, Status: store.MissionInProgress}))
+	must(t, s.Mission.UpdateTarget(ctx, &store.Target{ID: completed.Targets[0].ID, IsComplete: true}))
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: completed.ID, Status: store.MissionCompleted}))
+	wantErr(t, s.Mission.AddTarget(ctx, completed.ID, &store.Target{Name: "late", Country: "Poland"}), store.ErrMissionFinished)
+}
+
+func testNotes(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	mission := newMission(t, s, 2)
+	target := mission.Targets[0]
+
+	first := store.Note{TargetID: target.ID, Note: "first"}
+	must(t, s.Mission.AddNote(ctx, &first))
+	second := store.Note{TargetID: target.ID, Note: "second"}
+	must(t, s.Mission.AddNote(ctx, &second))
+
+	if first.ID == 0 || first.CreatedAt.IsZero() {
+		t.Fatalf("note was not created: %+v", first)
+	}
+
+	wantErr(t, s.Mission.AddNote(ctx, &store.Note{TargetID: target.ID + 1_000_000, Note: "lost"}), store.ErrorNotFound)
+
+	notes, err := s.Mission.GetAllTargetNotes(ctx, target.ID)
+	must(t, err)
+	if len(notes) != 2 || notes[0].ID != first.ID || notes[1].ID != second.ID {
+		t.Fatalf("got notes %+v, want first then second", notes)
+	}
+
+	edited := store.Note{ID: first.ID, Note: "edited"}
+	must(t, s.Mission.UpdateNote(ctx, &edited))
+	if edited.TargetID != target.ID || !edited.CreatedAt.Equal(first.CreatedAt) {
+		t.Fatalf("update did not return the row: %+v", edited)
+	}
+
+	got, err := s.Mission.GetNoteByID(ctx, first.ID)
+	must(t, err)
+	if got.Note != "edited" {
+		t.Fatalf("got note %q, want edited", got.Note)
+	}
+
+	withNotes, err := s.Mission.GetByIDWithNotes(ctx, mission.ID)
+	must(t, err)
+	if len(withNotes.Targets) != 2 || len(withNotes.Targets[0].Notes) != 2 {
+		t.Fatalf("got %+v, want notes on the first target", withNotes.Targets)
+	}
+	if withNotes.Targets[1].Notes == nil || len(withNotes.Targets[1].Notes) != 0 {
+		t.Fatalf("a target without notes should have an empty list, got %#v", withNotes.Targets[1].Notes)
+	}
+
+	must(t, s.Mission.RemoveNote(ctx, first.ID))
+	wantErr(t, s.Mission.RemoveNote(ctx, first.ID), store.ErrorNotFound)
+	wantErr(t, s.Mission.UpdateNote(ctx, &edited), store.ErrorNotFound)
+
+	_, err = s.Mission.GetNoteByID(ctx, first.ID)
+	wantErr(t, err, store.ErrorNotFound)
+	_, err = s.Mission.GetByIDWithNotes(ctx, mission.ID+1_000_000)
+	wantErr(t, err, store.ErrorNotFound)
+	wantErr(t, s.Mission.AddNote(ctx, &store.Note{TargetID: 1 << 40, Note: "lost"}), store.ErrorNotFound)
+
+	// notes freeze with the target, then with the mission
+	must(t, s.Mission.AssignCat(ctx, newCat(t, s, store.Cat{}).ID, mission.ID, store.AnyVersion))
+	must(t, s.Mission.UpdateTarget(ctx, &store.Target{ID: target.ID, IsComplete: true}))
+	wantErr(t, s.Mission.AddNote(ctx, &store.Note{TargetID: target.ID, Note: "late"}), store.ErrNotesFrozen)
+	wantErr(t, s.Mission.UpdateNote(ctx, &store.Note{ID: second.ID, Note: "late"}), store.ErrNotesFrozen)
+	wantErr(t, s.Mission.RemoveNote(ctx, second.ID), store.ErrNotesFrozen)
+
+	open := store.Note{TargetID: mission.Targets[1].ID, Note: "open"}
+	must(t, s.Mission.AddNote(ctx, &open))
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: mission.ID, Status: store.MissionAborted}))
+	wantErr(t, s.Mission.UpdateNote(ctx, &store.Note{ID: open.ID, Note: "late"}), store.ErrNotesFrozen)
+	wantErr(t, s.Mission.RemoveNote(ctx, open.ID), store.ErrNotesFrozen)
+}
+
+func testDeleteMissionCascades(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	mission := newMission(t, s, 2)
+	note := store.Note{TargetID: mission.Targets[0].ID, Note: "gone soon"}
+	must(t, s.Mission.AddNote(ctx, &note))
+
+	must(t, s.Mission.Delete(ctx, mission.ID, store.AnyVersion))
+	wantErr(t, s.Mission.Delete(ctx, mission.ID, store.AnyVersion), store.ErrorNotFound)
+
+	_, err := s.Mission.GetTargetByID(ctx, mission.Targets[0].ID)
+	wantErr(t, err, store.ErrorNotFound)
+	_, err = s.Mission.GetNoteByID(ctx, note.ID)
+	wantErr(t, err, store.ErrorNotFound)
+}
+
+func testRetireCat(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	cat := newCat(t, s, store.Cat{})
+	mission := newMission(t, s, 1)
+	must(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, store.AnyVersion))
+
+	busy := newCat(t, s, store.Cat{})
+	must(t, s.Mission.AssignCat(ctx, busy.ID, newMission(t, s, 1).ID, store.AnyVersion))
+	idle := newCat(t, s, store.Cat{})
+
+	wantErr(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion), store.ErrCatOnMission)
+	wantErr(t, s.Cat.Retire(ctx, cat.ID, store.AnyVersion, busy.ID), store.ErrCatBusy)
+	wantErr(t, s.Cat.Retire(ctx, cat.ID, store.AnyVersion, 1<<40), store.ErrInvalidSuccessor)
+
+	// the failed attempts changed nothing
+	got, err := s.Mission.GetByID(ctx, mission.ID)
+	must(t, err)
+	if got.CatID == nil || *got.CatID != cat.ID {
+		t.Fatalf("got spy %v, want %d still on the mission", got.CatID, cat.ID)
+	}
+	_, err = s.Cat.GetByID(ctx, cat.ID)
+	must(t, err)
+
+	version := got.Version
+	must(t, s.Cat.Retire(ctx, cat.ID, store.AnyVersion, idle.ID))
+
+	got, err = s.Mission.GetByID(ctx, mission.ID)
+	must(t, err)
+	if got.CatID == nil || *got.CatID != idle.ID {
+		t.Fatalf("got spy %v, want the mission handed over to %d", got.CatID, idle.ID)
+	}
+	if got.Status != store.MissionAssigned || got.Version == version {
+		t.Fatalf("got %s at version %d, want the mission still assigned at a new version", got.Status, got.Version)
+	}
+	_, err = s.Cat.GetByID(ctx, cat.ID)
+	wantErr(t, err, store.ErrorNotFound)
+
+	// an idle cat needs nobody to take over
+	must(t, s.Cat.Delete(ctx, newCat(t, s, store.Cat{}).ID, store.AnyVersion))
+}
+
+func testRetireToInvalidSuccessor(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	cat := newCat(t, s, store.Cat{})
+	mission := newMission(t, s, 1)
+	must(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, store.AnyVersion))
+
+	fired := newCat(t, s, store.Cat{})
+	must(t, s.Cat.Delete(ctx, fired.ID, store.AnyVersion))
+
+	for name, successorID := range map[string]int64{"itself": cat.ID, "fired": fired.ID, "missing": 1 << 40} {
+		if err := s.Cat.Retire(ctx, cat.ID, store.AnyVersion, successorID); !errors.Is(err, store.ErrInvalidSuccessor) {
+			t.Fatalf("%s: got error %v, want %v", name, err, store.ErrInvalidSuccessor)
+		}
+	}
+
+	got, err := s.Mission.GetByID(ctx, mission.ID)
+	must(t, err)
+	if got.CatID == nil || *got.CatID != cat.ID {
+		t.Fatalf("got spy %v, want %d still on the mission", got.CatID, cat.ID)
+	}
+	_, err = s.Cat.GetByID(ctx, cat.ID)
+	must(t, err)
+}
+
+// testCounts compares counts before and after, the database may hold other rows.
+func testCounts(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	counts := func() (int, map[store.MissionStatus]int) {
+		t.Helper()
+		idle, err := s.Cat.CountIdle(ctx)
+		must(t, err)
+		missions, err := s.Mission.CountByStatus(ctx)
+		must(t, err)
+		return idle, missions
+	}
+
+	idleBefore, missionsBefore := counts()
+
+	busy := newCat(t, s, store.Cat{})
+	newCat(t, s, store.Cat{})
+	newMission(t, s, 1)
+	assigned := newMission(t, s, 1)
+	must(t, s.Mission.AssignCat(ctx, busy.ID, assigned.ID, store.AnyVersion))
+
+	idleAfter, missionsAfter := counts()
+
+	if got := idleAfter - idleBefore; got != 1 {
+		t.Fatalf("idle cats grew by %d, want 1", got)
+	}
+	for status, want := range map[store.MissionStatus]int{store.MissionDraft: 1, store.MissionAssigned: 1, store.MissionInProgress: 0} {
+		if got := missionsAfter[status] - missionsBefore[status]; got != want {
+			t.Fatalf("%s missions grew by %d, want %d", status, got, want)
+		}
+	}
+}
+
+func testSalaryHistory(t *testing.T, s store.Storage) {
+	ctx := store.WithActor(context.Background(), "staff:alice")
+
+	cat := store.Cat{Name: "Tom", Breed: "Siamese", Salary: 1000}
+	must(t, s.Cat.Create(ctx, &cat))
+	must(t, s.Cat.Update(ctx, &store.Cat{ID: cat.ID, Salary: 1200.004}))
+	// the same salary again is no change
+	must(t, s.Cat.Update(ctx, &store.Cat{ID: cat.ID, Salary: 1200}))
+	must(t, s.Cat.Update(context.Background(), &store.Cat{ID: cat.ID, Salary: 900}))
+
+	history, err := s.Cat.SalaryHistory(ctx, cat.ID)
+	must(t, err)
+
+	want := []struct {
+		salary    float64
+		changedBy string
+	}{{1000, "staff:alice"}, {1200, "staff:alice"}, {900, store.SystemActor}}
+	if len(history) != len(want) {
+		t.Fatalf("got %d salary changes, want %d: %+v", len(history), len(want), history)
+	}
+	for i, w := range want {
+		h := history[i]
+		if h.CatID != cat.ID || h.Salary != w.salary || h.ChangedBy != w.changedBy {
+			t.Fatalf("change %d: got %+v, want salary %v by %s", i, h, w.salary, w.changedBy)
+		}
+		if i > 0 && h.EffectiveFrom.Before(history[i-1].EffectiveFrom) {
+			t.Fatalf("changes are not ordered: %+v", history)
+		}
+	}
+
+	_, err = s.Cat.SalaryHistory(ctx, 999999999)
+	wantErr(t, err, store.ErrorNotFound)
+
+	must(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion))
+	_, err = s.Cat.SalaryHistory(ctx, cat.ID)
+	wantErr(t, err, store.ErrorNotFound)
+}
+
+func testPayroll(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+	breed := uniqueBreed(t)
+
+	junior := newCat(t, s, store.Cat{Breed: breed, YearsOfExperience: 1, Salary: 100.10})
+	newCat(t, s, store.Cat{Breed: breed, YearsOfExperience: 2, Salary: 200.20})
+	newCat(t, s, store.Cat{Breed: breed, YearsOfExperience: 12, Salary: 1000})
+	must(t, s.Cat.Update(ctx, &store.Cat{ID: junior.ID, Salary: 150}))
+
+	now := time.Now().UTC()
+	lastMonth := now.AddDate(0, 0, -now.Day())
+
+	payroll, err := s.Cat.Payroll(ctx, lastMonth, now)
+	must(t, err)
+
+	var got []store.PayrollLine
+	for _, line := range payroll {
+		if line.Breed == breed {
+			got = append(got, line)
+		}
+	}
+
+	// the cats were hired this month, so last month costs nothing
+	month := now.Format(store.PayrollMonthLayout)
+	want := []store.PayrollLine{
+		{Month: month, Breed: breed, Experience: "0-2", Cats: 2, Total: 350.20},
+		{Month: month, Breed: breed, Experience: "11+", Cats: 1, Total: 1000},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got payroll %+v, want %+v", got, want)
+	}
+}
+
+func testVersions(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	cat := newCat(t, s, store.Cat{Salary: 100})
+	if cat.Version != 1 {
+		t.Fatalf("got cat version %d, want 1", cat.Version)
+	}
+
+	wantErr(t, s.Cat.Update(ctx, &store.Cat{ID: cat.ID, Salary: 200, Version: 2}), store.ErrVersionMismatch)
+	update := store.Cat{ID: cat.ID, Salary: 200, Version: 1}
+	must(t, s.Cat.Update(ctx, &update))
+	if update.Version != 2 {
+		t.Fatalf("got cat version %d after update, want 2", update.Version)
+	}
+	wantErr(t, s.Cat.Delete(ctx, cat.ID, 1), store.ErrVersionMismatch)
+
+	mission := newMission(t, s, 2)
+	if mission.Version != 1 || mission.Targets[0].Version != 1 {
+		t.Fatalf("got mission version %d and target version %d, want 1", mission.Version, mission.Targets[0].Version)
+	}
+
+	// the target list belongs to the mission's version
+	extra := store.Target{Name: "extra", Country: "Poland"}
+	must(t, s.Mission.AddTarget(ctx, mission.ID, &extra))
+	must(t, s.Mission.RemoveTarget(ctx, mission.Targets[1].ID, 1))
+	wantErr(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, 1), store.ErrVersionMismatch)
+
+	got, err := s.Mission.GetByID(ctx, mission.ID)
+	must(t, err)
+	if got.Version != 3 {
+		t.Fatalf("got mission version %d after adding and removing a target, want 3", got.Version)
+	}
+	must(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, got.Version))
+
+	target := mission.Targets[0]
+	target.IsComplete = true
+	target.Version = 2
+	wantErr(t, s.Mission.UpdateTarget(ctx, &target), store.ErrVersionMismatch)
+	target.Version = 1
+	must(t, s.Mission.UpdateTarget(ctx, &target))
+	if target.Version != 2 {
+		t.Fatalf("got target version %d after update, want 2", target.Version)
+	}
+	wantErr(t, s.Mission.RemoveTarget(ctx, extra.ID, 2), store.ErrVersionMismatch)
+
+	start := store.Mission{ID: mission.ID, Status: store.MissionInProgress, Version: 3}
+	wantErr(t, s.Mission.Update(ctx, &start), store.ErrVersionMismatch)
+	start.Version = 4
+	must(t, s.Mission.Update(ctx, &start))
+	if start.Version != 5 {
+		t.Fatalf("got mission version %d after starting, want 5", start.Version)
+	}
+
+	wantErr(t, s.Mission.Delete(ctx, mission.ID, 5), store.ErrMissionHasSpy)
+	draft := newMission(t, s, 1)
+	wantErr(t, s.Mission.Delete(ctx, draft.ID, 2), store.ErrVersionMismatch)
+	wantErr(t, s.Mission.Delete(ctx, mission.ID+1_000_000, 1), store.ErrorNotFound)
+	wantErr(t, s.Cat.Delete(ctx, cat.ID+1_000_000, 1), store.ErrorNotFound)
+}
+
+func testIdempotency(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+	owner := uniqueBreed(t)
+
+	first := &store.IdempotentRequest{Owner: owner, Key: "retry-me", RequestHash: "hash"}
+	existing, err := s.Idempotency.Claim(ctx, first, time.Hour)
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("got %+v, want a fresh claim", existing)
+	}
+
+	retry := &store.IdempotentRequest{Owner: owner, Key: "retry-me", RequestHash: "hash"}
+	existing, err = s.Idempotency.Claim(ctx, retry, time.Hour)
+	must(t, err)
+	if existing == nil || existing.Done() || existing.RequestHash != "hash" {
+		t.Fatalf("got %+v, want the claim in progress", existing)
+	}
+
+	// the same key of someone else is another request
+	other := &store.IdempotentRequest{Owner: owner + "-other", Key: "retry-me", RequestHash: "other"}
+	existing, err = s.Idempotency.Claim(ctx, other, time.Hour)
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("got %+v, want keys apart per owner", existing)
+	}
+
+	first.Status = 201
+	first.Header = map[string]string{"Content-Type": "application/json"}
+	first.Body = []byte(`{"id":1}`)
+	must(t, s.Idempotency.Complete(ctx, first))
+
+	existing, err = s.Idempotency.Claim(ctx, retry, time.Hour)
+	must(t, err)
+	if existing == nil || existing.Status != 201 || string(existing.Body) != `{"id":1}` || existing.Header["Content-Type"] != "application/json" {
+		t.Fatalf("got %+v, want the stored response", existing)
+	}
+
+	// a completed request is kept, a failed one is given up
+	must(t, s.Idempotency.Release(ctx, first))
+	must(t, s.Idempotency.Release(ctx, other))
+	existing, err = s.Idempotency.Claim(ctx, retry, time.Hour)
+	must(t, err)
+	if existing == nil || !existing.Done() {
+		t.Fatalf("got %+v, want the completed request to survive release", existing)
+	}
+	existing, err = s.Idempotency.Claim(ctx, other, time.Hour)
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("got %+v, want the released key free", existing)
+	}
+
+	expired := &store.IdempotentRequest{Owner: owner, Key: "expired", RequestHash: "old"}
+	_, err = s.Idempotency.Claim(ctx, expired, -time.Second)
+	must(t, err)
+	deleted, err := s.Idempotency.DeleteExpired(ctx)
+	must(t, err)
+	if deleted < 1 {
+		t.Fatalf("deleted %d expired keys, want at least 1", deleted)
+	}
+
+	_, err = s.Idempotency.Claim(ctx, expired, -time.Second)
+	must(t, err)
+	renewed := &store.IdempotentRequest{Owner: owner, Key: "expired", RequestHash: "new"}
+	existing, err = s.Idempotency.Claim(ctx, renewed, time.Hour)
+	must(t, err)
+	if existing != nil {
+		t.Fatalf("got %+v, want the expired key taken over", existing)
+	}
+}
+
+func testAudit(t *testing.T, s store.Storage) {
+	actor := "staff:" + uniqueBreed(t)
+	ctx := store.WithRequestID(store.WithActor(context.Background(), actor), "req-1")
+	start := time.Now().Add(-time.Minute)
+
+	cat := store.Cat{Name: "Tom", Breed: "Siamese", Salary: 1000}
+	must(t, s.Cat.Create(ctx, &cat))
+	must(t, s.Cat.Update(ctx, &store.Cat{ID: cat.ID, Salary: 1200, Version: store.AnyVersion}))
+
+	mission := store.Mission{Targets: []store.Target{{Name: "Jerry", Country: "USA"}}}
+	must(t, s.Mission.Create(ctx, &mission))
+	must(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, store.AnyVersion))
+
+	target := store.Target{Name: "Spike", Country: "USA"}
+	must(t, s.Mission.AddTarget(ctx, mission.ID, &target))
+	note := store.Note{TargetID: target.ID, Note: "seen"}
+	must(t, s.Mission.AddNote(ctx, &note))
+	must(t, s.Mission.UpdateNote(ctx, &store.Note{ID: note.ID, Note: "seen twice"}))
+	must(t, s.Mission.RemoveNote(ctx, note.ID))
+	must(t, s.Mission.RemoveTarget(ctx, target.ID, store.AnyVersion))
+	jerry := mission.Targets[0].ID
+	must(t, s.Mission.UpdateTarget(ctx, &store.Target{ID: jerry, IsComplete: true, Version: store.AnyVersion}))
+
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: mission.ID, Status: store.MissionAborted}))
+
+	// a mission with a spy can't be deleted, a draft created by someone else can
+	draft := newMission(t, s, 1)
+	must(t, s.Mission.Delete(ctx, draft.ID, store.AnyVersion))
+	must(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion))
+
+	// a failed change leaves no entry
+	wantErr(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion), store.ErrorNotFound)
+
+	page, err := s.Audit.List(ctx, store.AuditFilter{Actor: actor})
+	must(t, err)
+
+	want := []struct {
+		action store.AuditAction
+		entity store.AuditEntity
+		id     int64
+	}{
+		{store.AuditDelete, store.AuditCat, cat.ID},
+		{store.AuditDelete, store.AuditMission, draft.ID},
+		{store.AuditUpdate, store.AuditMission, mission.ID},
+		{store.AuditUpdate, store.AuditTarget, jerry},
+		{store.AuditDelete, store.AuditTarget, target.ID},
+		{store.AuditDelete, store.AuditNote, note.ID},
+		{store.AuditUpdate, store.AuditNote, note.ID},
+		{store.AuditCreate, store.AuditNote, note.ID},
+		{store.AuditCreate, store.AuditTarget, target.ID},
+		{store.AuditAssign, store.AuditMission, mission.ID},
+		{store.AuditCreate, store.AuditMission, mission.ID},
+		{store.AuditUpdate, store.AuditCat, cat.ID},
+		{store.AuditCreate, store.AuditCat, cat.ID},
+	}
+	if len(page.Data) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(page.Data), len(want), page.Data)
+	}
+	for i, w := range want {
+		e := page.Data[i]
+		if e.Action != w.action || e.Entity != w.entity || e.EntityID != w.id || e.Actor != actor || e.RequestID != "req-1" {
+			t.Fatalf("entry %d: got %+v, want %s %s %d", i, e, w.action, w.entity, w.id)
+		}
+		if (e.Before == nil) != (w.action == store.AuditCreate) || (e.After == nil) != (w.action == store.AuditDelete) {
+			t.Fatalf("entry %d: got before %s and after %s for %s", i, e.Before, e.After, e.Action)
+		}
+	}
+
+	var before, after store.Cat
+	salary := page.Data[len(want)-2]
+	must(t, json.Unmarshal(salary.Before, &before))
+	must(t, json.Unmarshal(salary.After, &after))
+	if before.Salary != 1000 || after.Salary != 1200 || after.Version != before.Version+1 {
+		t.Fatalf("got salary change %s -> %s, want 1000 -> 1200 with the version bumped", salary.Before, salary.After)
+	}
+
+	var assigned store.Mission
+	must(t, json.Unmarshal(page.Data[len(want)-4].After, &assigned))
+	if assigned.CatID == nil || *assigned.CatID != cat.ID || assigned.Status != store.MissionAssigned {
+		t.Fatalf("got assigned mission %s, want cat %d", page.Data[len(want)-4].After, cat.ID)
+	}
+
+	// filters and pages
+	page, err = s.Audit.List(ctx, store.AuditFilter{Actor: actor, Entity: store.AuditCat, EntityID: cat.ID, Limit: 2})
+	must(t, err)
+	if len(page.Data) != 2 || page.NextCursor == "" {
+		t.Fatalf("got %+v, want a full page and a cursor", page)
+	}
+	page, err = s.Audit.List(ctx, store.AuditFilter{Actor: actor, Entity: store.AuditCat, EntityID: cat.ID, Limit: 2, Cursor: page.NextCursor})
+	must(t, err)
+	if len(page.Data) != 1 || page.Data[0].Action != store.AuditCreate || page.NextCursor != "" {
+		t.Fatalf("got %+v, want the create entry last", page)
+	}
+
+	page, err = s.Audit.List(ctx, store.AuditFilter{Actor: actor, Action: store.AuditUpdate})
+	must(t, err)
+	if len(page.Data) != 4 {
+		t.Fatalf("got %d updates, want 4", len(page.Data))
+	}
+
+	page, err = s.Audit.List(ctx, store.AuditFilter{Actor: actor, From: start, To: time.Now().Add(time.Minute)})
+	must(t, err)
+	if len(page.Data) != len(want) {
+		t.Fatalf("got %d entries in the period, want %d", len(page.Data), len(want))
+	}
+	page, err = s.Audit.List(ctx, store.AuditFilter{Actor: actor, To: start})
+	must(t, err)
+	if len(page.Data) != 0 {
+		t.Fatalf("got %d entries before the test started, want none", len(page.Data))
+	}
+
+	// the same instants in other zones pick the same entries
+	east, west := time.FixedZone("east", 2*60*60), time.FixedZone("west", -5*60*60)
+	page, err = s.Audit.List(ctx, store.AuditFilter{Actor: actor, From: start.In(east), To: time.Now().Add(time.Minute).In(west)})
+	must(t, err)
+	if len(page.Data) != len(want) {
+		t.Fatalf("got %d entries in the period with offsets, want %d", len(page.Data), len(want))
+	}
+	page, err = s.Audit.List(ctx, store.AuditFilter{Actor: actor, From: time.Now().Add(time.Minute).In(east)})
+	must(t, err)
+	if len(page.Data) != 0 {
+		t.Fatalf("got %d entries after the test, want none", len(page.Data))
+	}
+
+	_, err = s.Audit.List(ctx, store.AuditFilter{Cursor: "garbage"})
+	wantErr(t, err, store.ErrInvalidCursor)
+}
+
+func testSoftDelete(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+	breed := uniqueBreed(t)
+
+	cat := newCat(t, s, store.Cat{Breed: breed})
+	mission := newMission(t, s, 1)
+	must(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, store.AnyVersion))
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: mission.ID, Status: store.MissionInProgress}))
+	must(t, s.Mission.UpdateTarget(ctx, &store.Target{ID: mission.Targets[0].ID, IsComplete: true}))
+	must(t, s.Mission.Update(ctx, &store.Mission{ID: mission.ID, Status: store.MissionCompleted}))
+
+	must(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion))
+	wantErr(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion), store.ErrorNotFound)
+
+	_, err := s.Cat.GetByID(ctx, cat.ID)
+	wantErr(t, err, store.ErrorNotFound)
+	_, err = s.Cat.SalaryHistory(ctx, cat.ID)
+	wantErr(t, err, store.ErrorNotFound)
+
+	page, err := s.Cat.List(ctx, store.CatFilter{Breed: breed})
+	must(t, err)
+	if len(page.Data) != 0 {
+		t.Fatalf("got %+v, want deleted cats left out", page.Data)
+	}
+	page, err = s.Cat.List(ctx, store.CatFilter{Breed: breed, IncludeDeleted: true})
+	must(t, err)
+	if len(page.Data) != 1 || page.Data[0].DeletedAt == nil {
+		t.Fatalf("got %+v, want the deleted cat with its deletion time", page.Data)
+	}
+
+	// the finished mission still tells who ran it
+	finished, err := s.Mission.GetByID(ctx, mission.ID)
+	must(t, err)
+	if finished.CatID == nil || *finished.CatID != cat.ID {
+		t.Fatalf("got cat %v on the finished mission, want %d", finished.CatID, cat.ID)
+	}
+
+	restored, err := s.Cat.Restore(ctx, cat.ID)
+	must(t, err)
+	if restored.ID != cat.ID || restored.DeletedAt != nil || restored.Version != cat.Version+2 {
+		t.Fatalf("got %+v, want the cat back at version %d", restored, cat.Version+2)
+	}
+	_, err = s.Cat.Restore(ctx, cat.ID)
+	wantErr(t, err, store.ErrNotDeleted)
+	_, err = s.Cat.Restore(ctx, 999999999)
+	wantErr(t, err, store.ErrorNotFound)
+
+	history, err := s.Cat.SalaryHistory(ctx, cat.ID)
+	must(t, err)
+	if len(history) != 1 {
+		t.Fatalf("got %d salary changes, want the history kept", len(history))
+	}
+
+	draft := newMission(t, s, 2)
+	note := store.Note{TargetID: draft.Targets[0].ID, Note: "kept"}
+	must(t, s.Mission.AddNote(ctx, &note))
+	must(t, s.Mission.Delete(ctx, draft.ID, store.AnyVersion))
+
+	_, err = s.Mission.GetByIDWithTargets(ctx, draft.ID)
+	wantErr(t, err, store.ErrorNotFound)
+	wantErr(t, s.Mission.AddNote(ctx, &store.Note{TargetID: draft.Targets[1].ID, Note: "too late"}), store.ErrorNotFound)
+	wantErr(t, s.Mission.UpdateTarget(ctx, &store.Target{ID: draft.Targets[1].ID, IsComplete: true}), store.ErrorNotFound)
+	wantErr(t, s.Mission.AddTarget(ctx, draft.ID, &store.Target{Name: "late", Country: "USA"}), store.ErrorNotFound)
+
+	listed := func(filter store.MissionFilter) *store.Mission {
+		t.Helper()
+		for {
+			page, err := s.Mission.ListWithTargets(ctx, filter)
+			must(t, err)
+			for _, m := range page.Data {
+				if m.ID == draft.ID {
+					return &m
+				}
+			}
+			if page.NextCursor == "" {
+				return nil
+			}
+			filter.Cursor = page.NextCursor
+		}
+	}
+	if m := listed(store.MissionFilter{Limit: store.MaxPageLimit}); m != nil {
+		t.Fatalf("got %+v, want deleted missions left out", m)
+	}
+	if m := listed(store.MissionFilter{Limit: store.MaxPageLimit, IncludeDeleted: true}); m == nil || m.DeletedAt == nil || len(m.Targets) != 2 {
+		t.Fatalf("got %+v, want the deleted mission listed on request", m)
+	}
+
+	back, err := s.Mission.Restore(ctx, draft.ID)
+	must(t, err)
+	if back.DeletedAt != nil || back.Status != store.MissionDraft {
+		t.Fatalf("got %+v, want the draft back", back)
+	}
+	notes, err := s.Mission.GetAllTargetNotes(ctx, draft.Targets[0].ID)
+	must(t, err)
+	if len(notes) != 1 || notes[0].ID != note.ID {
+		t.Fatalf("got notes %+v, want the note back with its mission", notes)
+	}
+
+	must(t, s.Mission.Delete(ctx, draft.ID, store.AnyVersion))
+	must(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion))
+
+	// nothing was deleted long enough ago
+	_, err = s.Cat.Purge(ctx, time.Now().Add(-time.Hour))
+	must(t, err)
+	_, err = s.Cat.Restore(ctx, cat.ID)
+	must(t, err)
+	must(t, s.Cat.Delete(ctx, cat.ID, store.AnyVersion))
+
+	purged, err := s.Cat.Purge(ctx, time.Now().Add(time.Minute))
+	must(t, err)
+	if purged < 1 {
+		t.Fatalf("purged %d cats, want at least the deleted one", purged)
+	}
+	purged, err = s.Mission.Purge(ctx, time.Now().Add(time.Minute))
+	must(t, err)
+	if purged < 1 {
+		t.Fatalf("purged %d missions, want at least the deleted one", purged)
+	}
+
+	_, err = s.Cat.Restore(ctx, cat.ID)
+	wantErr(t, err, store.ErrorNotFound)
+	_, err = s.Mission.Restore(ctx, draft.ID)
+	wantErr(t, err, store.ErrorNotFound)
+}
+
+func testDeleteMissionWithSpy(t *testing.T, s store.Storage) {
+	ctx := context.Background()
+
+	cat := newCat(t, s, store.Cat{})
+	mission := newMission(t, s, 1)
+	must(t, s.Mission.AssignCat(ctx, cat.ID, mission.ID, store.AnyVersion))
+
+	// AnyVersion skips the version check, not the spy check
+	wantErr(t, s.Mission.Delete(ctx, mission.ID, store.AnyVersion), store.ErrMissionHasSpy)
+
+	_, err := s.Mission.GetByID(ctx, mission.ID)
+	must(t, err)
+	busy, err := s.Cat.HasIncompleteMission(ctx, cat.ID)
+	must(t, err)
+	if !busy {
+		t.Fatal("cat lost its mission to a rejected delete")
+	}
+
+	// the cat is still on the first mission, so it can't take another
+	wantErr(t, s.Mission.AssignCat(ctx, cat.ID, newMission(t, s, 1).ID, store.AnyVersion), store.ErrCatBusy)
+}
@@ -10,16 +10,20 @@ import (
 type Target struct {
 	ID         int64  `json:"id"`
 	MissionID  int64  `json:"mission_id"`
-	Name       string `json:"name"`
-	Country    string `json:"country"`
+	Name       string `json:"name" validate:"notblank,max=255"`
+	Country    string `json:"country" validate:"notblank,max=255"`
 	IsComplete bool   `json:"is_complete"`
+	Version    int64  `json:"version"`
+	// Notes is only filled when notes are requested explicitly.
+	Notes []Note `json:"notes,omitempty"`
 }
 
 func (ms *MissionStore) GetTargetByID(ctx context.Context, id int64) (*Target, error) {
 	query := `
-	SELECT id, mission_id, name, country, is_complete
-	FROM targets
-	WHERE id = $1;
+	SELECT t.id, t.mission_id, t.name, t.country, t.is_complete, t.version
+	FROM targets t
+	JOIN missions m ON m.id = t.mission_id
+	WHERE t.id = $1 AND m.deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -33,6 +37,7 @@ func (ms *MissionStore) GetTargetByID(ctx context.Context, id int64) (*Target, e
 			&target.Name,
 			&target.Country,
 			&target.IsComplete,
+			&target.Version,
 		)
 	if err != nil {
 		switch {
@@ -48,9 +53,10 @@ func (ms *MissionStore) GetTargetByID(ctx context.Context, id int64) (*Target, e
 
 func (ms *MissionStore) GetAllMissionTargets(ctx context.Context, missionID int64) ([]Target, error) {
 	query := `
-		SELECT id, mission_id, name, country, is_complete
-		FROM targets
-		WHERE mission_id = $1;
+		SELECT t.id, t.mission_id, t.name, t.country, t.is_complete, t.version
+		FROM targets t
+		JOIN missions m ON m.id = t.mission_id
+		WHERE t.mission_id = $1 AND m.deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -65,7 +71,7 @@ func (ms *MissionStore) GetAllMissionTargets(ctx context.Context, missionID int6
 	targets := []Target{}
 	for rows.Next() {
 		var t Target
-		err = rows.Scan(&t.ID, &t.MissionID, &t.Name, &t.Country, &t.IsComplete)
+		err = rows.Scan(&t.ID, &t.MissionID, &t.Name, &t.Country, &t.IsComplete, &t.Version)
 		if err != nil {
 			return nil, fmt.Errorf("store: failed to scan row: %w", err)
 		}
@@ -77,9 +83,10 @@ func (ms *MissionStore) GetAllMissionTargets(ctx context.Context, missionID int6
 
 func (ms *MissionStore) GetTargetsQuantity(ctx context.Context, missionID int64) (int, error) {
 	query := `
-		SELECT COUNT(id)
-		FROM targets
-		WHERE mission_id = $1;
+		SELECT COUNT(t.id)
+		FROM targets t
+		JOIN missions m ON m.id = t.mission_id
+		WHERE t.mission_id = $1 AND m.deleted_at IS NULL;
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)